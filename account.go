@@ -1,16 +1,16 @@
 package tractive
 
 import (
-	"encoding/json"
-	"fmt"
+	"context"
+	"net/http"
 
 	"github.com/insomniacslk/xjson"
 )
 
 type Envelope struct {
-	ID      string `json:"_id"`
-	Version string `json:"_version"`
-	Type    string `json:"_type"`
+	ID      string `json:"_id,omitempty"`
+	Version string `json:"_version,omitempty"`
+	Type    string `json:"_type,omitempty"`
 }
 
 type AccountInfoSubSettings struct {
@@ -137,57 +137,37 @@ type AccountSharesResponse []struct {
 }
 
 func (t *Tractive) GetAccountInfo() (*AccountInfoResponse, error) {
-	u := getTractiveURL()
-	u.Path = "/4/user/" + t.UserID
-	body, err := tractiveRequest("GET", u, t.Token)
-	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
-	}
-	var resp AccountInfoResponse
-	if err := json.Unmarshal(body, &resp); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal json response: %w", err)
-	}
-	return &resp, nil
+	return t.GetAccountInfoContext(context.Background())
+}
+
+func (t *Tractive) GetAccountInfoContext(ctx context.Context) (*AccountInfoResponse, error) {
+	u := t.tractiveURL("/4/user/" + t.UserID)
+	return callAPI[AccountInfoResponse](ctx, t, http.MethodGet, u, nil)
 }
 
 func (t *Tractive) GetAccountSubscriptions() (*AccountSubscriptionsResponse, error) {
-	u := getTractiveURL()
-	u.Path = "/4/user/" + t.UserID + "/subscriptions"
-	body, err := tractiveRequest("GET", u, t.Token)
-	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
-	}
-	var resp AccountSubscriptionsResponse
-	if err := json.Unmarshal(body, &resp); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal json response: %w", err)
-	}
-	return &resp, nil
+	return t.GetAccountSubscriptionsContext(context.Background())
+}
+
+func (t *Tractive) GetAccountSubscriptionsContext(ctx context.Context) (*AccountSubscriptionsResponse, error) {
+	u := t.tractiveURL("/4/user/" + t.UserID + "/subscriptions")
+	return callAPI[AccountSubscriptionsResponse](ctx, t, http.MethodGet, u, nil)
 }
 
 func (t *Tractive) GetAccountSubscription(subscriptionID string) (*AccountSubscriptionResponse, error) {
-	u := getTractiveURL()
-	u.Path = "/4/subscription/" + subscriptionID
-	body, err := tractiveRequest("GET", u, t.Token)
-	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
-	}
-	var resp AccountSubscriptionResponse
-	if err := json.Unmarshal(body, &resp); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal json response: %w", err)
-	}
-	return &resp, nil
+	return t.GetAccountSubscriptionContext(context.Background(), subscriptionID)
+}
+
+func (t *Tractive) GetAccountSubscriptionContext(ctx context.Context, subscriptionID string) (*AccountSubscriptionResponse, error) {
+	u := t.tractiveURL("/4/subscription/" + subscriptionID)
+	return callAPI[AccountSubscriptionResponse](ctx, t, http.MethodGet, u, nil)
 }
 
 func (t *Tractive) GetAccountShares() (*AccountSharesResponse, error) {
-	u := getTractiveURL()
-	u.Path = "/4/user/" + t.UserID + "/shares"
-	body, err := tractiveRequest("GET", u, t.Token)
-	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
-	}
-	var resp AccountSharesResponse
-	if err := json.Unmarshal(body, &resp); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal json response: %w", err)
-	}
-	return &resp, nil
+	return t.GetAccountSharesContext(context.Background())
+}
+
+func (t *Tractive) GetAccountSharesContext(ctx context.Context) (*AccountSharesResponse, error) {
+	u := t.tractiveURL("/4/user/" + t.UserID + "/shares")
+	return callAPI[AccountSharesResponse](ctx, t, http.MethodGet, u, nil)
 }
@@ -0,0 +1,15 @@
+// Package analysis turns tracker position series into higher level
+// information, like the places where a pet stayed and the trips between
+// them.
+package analysis
+
+import (
+	"github.com/insomniacslk/tractive"
+	"github.com/insomniacslk/tractive/geo"
+)
+
+// Distance returns the great-circle distance in meters between two
+// positions.
+func Distance(a, b tractive.TrackerPosition) float64 {
+	return geo.Haversine(geo.PositionPoint(a), geo.PositionPoint(b))
+}
@@ -0,0 +1,159 @@
+package analysis
+
+import (
+	"math"
+
+	"github.com/insomniacslk/tractive"
+)
+
+// Filter transforms a time-ordered position series. Filters return a new
+// slice and don't modify their input.
+type Filter func([]tractive.TrackerPosition) []tractive.TrackerPosition
+
+// Pipeline is a list of filters applied in order.
+type Pipeline []Filter
+
+// Apply runs the filters of the pipeline on a position series.
+func (p Pipeline) Apply(positions []tractive.TrackerPosition) []tractive.TrackerPosition {
+	for _, f := range p {
+		positions = f(positions)
+	}
+	return positions
+}
+
+// ApplyHistory runs the filters of the pipeline on every segment of a
+// position history.
+func (p Pipeline) ApplyHistory(h *tractive.PositionHistory) *tractive.PositionHistory {
+	var segments [][]tractive.TrackerPosition
+	for _, s := range h.Segments() {
+		segments = append(segments, p.Apply(s))
+	}
+	return tractive.NewPositionHistory(segments)
+}
+
+// FilterOptions selects the filters of a pipeline built by NewPipeline.
+// Zero-valued fields disable the corresponding filter.
+type FilterOptions struct {
+	// MaxUncertainty drops the positions whose uncertainty is larger, in
+	// meters.
+	MaxUncertainty int
+	// Dedup drops consecutive positions at the same coordinates.
+	Dedup bool
+	// MaxSpeed drops the positions that would imply a larger speed, in
+	// meters per second.
+	MaxSpeed float64
+	// Smooth applies a Kalman smoother, with the given process noise in
+	// meters per second.
+	Smooth float64
+}
+
+// NewPipeline returns a pipeline made of the filters selected by opts, in an
+// order where every filter benefits from the previous ones: accuracy,
+// duplicates, speed, smoothing.
+func NewPipeline(opts FilterOptions) Pipeline {
+	var p Pipeline
+	if opts.MaxUncertainty > 0 {
+		p = append(p, MaxUncertainty(opts.MaxUncertainty))
+	}
+	if opts.Dedup {
+		p = append(p, Dedup())
+	}
+	if opts.MaxSpeed > 0 {
+		p = append(p, MaxSpeed(opts.MaxSpeed))
+	}
+	if opts.Smooth > 0 {
+		p = append(p, KalmanSmoother(opts.Smooth))
+	}
+	return p
+}
+
+// MaxUncertainty drops the positions with an uncertainty larger than the
+// given number of meters, like most cell and Wi-Fi based fixes.
+func MaxUncertainty(meters int) Filter {
+	return func(positions []tractive.TrackerPosition) []tractive.TrackerPosition {
+		var out []tractive.TrackerPosition
+		for _, p := range positions {
+			if p.PosUncertainty <= meters {
+				out = append(out, p)
+			}
+		}
+		return out
+	}
+}
+
+// Dedup drops the positions at the same coordinates as the previous one.
+func Dedup() Filter {
+	return func(positions []tractive.TrackerPosition) []tractive.TrackerPosition {
+		var out []tractive.TrackerPosition
+		for _, p := range positions {
+			if len(out) > 0 && out[len(out)-1].LatLong == p.LatLong {
+				continue
+			}
+			out = append(out, p)
+		}
+		return out
+	}
+}
+
+// MaxSpeed drops the positions that can only be reached from the previous
+// kept one at a speed larger than the given meters per second. The first
+// position of a series has no previous one to be checked against, so it is
+// dropped instead if the two positions following it are consistent with each
+// other but not with it.
+func MaxSpeed(mps float64) Filter {
+	tooFast := func(a, b tractive.TrackerPosition) bool {
+		d := Distance(a, b)
+		dt := float64(b.Time - a.Time)
+		return (dt <= 0 && d > 0) || (dt > 0 && d/dt > mps)
+	}
+	return func(positions []tractive.TrackerPosition) []tractive.TrackerPosition {
+		var out []tractive.TrackerPosition
+		for i, p := range positions {
+			if len(out) > 0 && tooFast(out[len(out)-1], p) {
+				if len(out) > 1 || i+1 == len(positions) ||
+					tooFast(p, positions[i+1]) || !tooFast(out[0], positions[i+1]) {
+					continue
+				}
+				out = out[:0]
+			}
+			out = append(out, p)
+		}
+		return out
+	}
+}
+
+// KalmanSmoother smooths the coordinates of the positions with a Kalman
+// filter, using their uncertainty as measurement noise. processNoise, in
+// meters per second, is how fast the position is expected to change: lower
+// values smooth more.
+func KalmanSmoother(processNoise float64) Filter {
+	return func(positions []tractive.TrackerPosition) []tractive.TrackerPosition {
+		out := make([]tractive.TrackerPosition, 0, len(positions))
+		var (
+			lat, lon float64
+			// variance is the variance of the estimate, in square
+			// meters. Negative means uninitialized.
+			variance = -1.0
+			last     int64
+		)
+		for _, p := range positions {
+			accuracy := math.Max(1, float64(p.PosUncertainty))
+			if variance < 0 {
+				lat, lon = p.LatLong[0], p.LatLong[1]
+				variance = accuracy * accuracy
+			} else {
+				if dt := p.Time - last; dt > 0 {
+					variance += float64(dt) * processNoise * processNoise
+				}
+				k := variance / (variance + accuracy*accuracy)
+				lat += k * (p.LatLong[0] - lat)
+				lon += k * (p.LatLong[1] - lon)
+				variance *= 1 - k
+			}
+			last = p.Time
+			p.LatLong = [2]float64{lat, lon}
+			out = append(out, p)
+		}
+		return out
+	}
+}
@@ -0,0 +1,99 @@
+package analysis
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/insomniacslk/tractive"
+)
+
+// pos returns a position at time ts, step times about 7.4 meters east of a
+// fixed point.
+func pos(ts int64, step int, uncertainty int) tractive.TrackerPosition {
+	return tractive.TrackerPosition{
+		Time:           ts,
+		LatLong:        [2]float64{48.2, 16.3 + float64(step)*0.0001},
+		PosUncertainty: uncertainty,
+	}
+}
+
+func TestFilters(t *testing.T) {
+	outlier := tractive.TrackerPosition{Time: 0, LatLong: [2]float64{10, 10}}
+	for _, tt := range []struct {
+		name   string
+		filter Filter
+		in     []tractive.TrackerPosition
+		want   []tractive.TrackerPosition
+	}{
+		{
+			name:   "max uncertainty",
+			filter: MaxUncertainty(50),
+			in:     []tractive.TrackerPosition{pos(0, 0, 10), pos(10, 1, 50), pos(20, 2, 51), pos(30, 3, 500)},
+			want:   []tractive.TrackerPosition{pos(0, 0, 10), pos(10, 1, 50)},
+		},
+		{
+			name:   "dedup",
+			filter: Dedup(),
+			in:     []tractive.TrackerPosition{pos(0, 0, 0), pos(10, 0, 0), pos(20, 1, 0), pos(30, 0, 0), pos(40, 0, 0)},
+			want:   []tractive.TrackerPosition{pos(0, 0, 0), pos(20, 1, 0), pos(30, 0, 0)},
+		},
+		{
+			name:   "max speed outlier at the start",
+			filter: MaxSpeed(5),
+			in:     []tractive.TrackerPosition{outlier, pos(10, 0, 0), pos(20, 1, 0), pos(30, 2, 0), pos(40, 3, 0)},
+			want:   []tractive.TrackerPosition{pos(10, 0, 0), pos(20, 1, 0), pos(30, 2, 0), pos(40, 3, 0)},
+		},
+		{
+			name:   "max speed outlier in the middle",
+			filter: MaxSpeed(5),
+			in:     []tractive.TrackerPosition{pos(0, 0, 0), pos(10, 1, 0), {Time: 20, LatLong: [2]float64{10, 10}}, pos(30, 2, 0)},
+			want:   []tractive.TrackerPosition{pos(0, 0, 0), pos(10, 1, 0), pos(30, 2, 0)},
+		},
+		{
+			name:   "max speed keeps a lone first position",
+			filter: MaxSpeed(5),
+			in:     []tractive.TrackerPosition{outlier, pos(10, 0, 0)},
+			want:   []tractive.TrackerPosition{outlier},
+		},
+		{
+			name:   "max speed duplicate timestamps",
+			filter: MaxSpeed(5),
+			in:     []tractive.TrackerPosition{pos(0, 0, 0), pos(10, 1, 0), pos(10, 1, 0), pos(10, 2, 0), pos(20, 2, 0)},
+			want:   []tractive.TrackerPosition{pos(0, 0, 0), pos(10, 1, 0), pos(10, 1, 0), pos(20, 2, 0)},
+		},
+		{
+			name:   "empty",
+			filter: MaxSpeed(5),
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			in := append([]tractive.TrackerPosition(nil), tt.in...)
+			got := tt.filter(in)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+			if !reflect.DeepEqual(in, tt.in) {
+				t.Errorf("the filter modified its input")
+			}
+		})
+	}
+}
+
+func TestNewPipeline(t *testing.T) {
+	p := NewPipeline(FilterOptions{MaxUncertainty: 50, Dedup: true, MaxSpeed: 5})
+	in := []tractive.TrackerPosition{
+		{Time: 0, LatLong: [2]float64{10, 10}},
+		pos(10, 0, 10),
+		pos(20, 0, 10),
+		pos(30, 1, 500),
+		pos(40, 1, 10),
+		pos(50, 2, 10),
+	}
+	want := []tractive.TrackerPosition{pos(10, 0, 10), pos(40, 1, 10), pos(50, 2, 10)}
+	if got := p.Apply(in); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if p := NewPipeline(FilterOptions{}); len(p) != 0 {
+		t.Errorf("got %d filters with no options, want 0", len(p))
+	}
+}
@@ -0,0 +1,139 @@
+package analysis
+
+import (
+	"time"
+
+	"github.com/insomniacslk/tractive"
+)
+
+// SegmentOptions controls how a position series is split into stays and
+// trips.
+type SegmentOptions struct {
+	// StayRadius is the maximum distance in meters from the first position
+	// of a stay for the following positions to be part of it.
+	StayRadius float64
+	// MinStayDuration is the minimum duration of a stay. Shorter stops are
+	// part of a trip.
+	MinStayDuration time.Duration
+}
+
+// DefaultSegmentOptions are reasonable options for pets.
+var DefaultSegmentOptions = SegmentOptions{
+	StayRadius:      50,
+	MinStayDuration: 10 * time.Minute,
+}
+
+// Stay is a period spent within a small area.
+type Stay struct {
+	Start time.Time
+	End   time.Time
+	// Lat and Lon are the centroid of the positions of the stay.
+	Lat float64
+	Lon float64
+	// Positions is the number of positions of the stay.
+	Positions int
+}
+
+func (s *Stay) Duration() time.Duration {
+	return s.End.Sub(s.Start)
+}
+
+// Trip is the movement between two stays.
+type Trip struct {
+	Start time.Time
+	End   time.Time
+	// From and To are the stays the trip starts from and ends at. From is
+	// nil if the series starts with the trip, and To is nil if it ends
+	// with it.
+	From *Stay
+	To   *Stay
+	// Distance is the distance covered in meters.
+	Distance float64
+	// MaxSpeed and AvgSpeed are in meters per second.
+	MaxSpeed float64
+	AvgSpeed float64
+	// Positions is the number of positions of the trip.
+	Positions int
+}
+
+func (t *Trip) Duration() time.Duration {
+	return t.End.Sub(t.Start)
+}
+
+// Segment splits a time-ordered position series into stays, i.e. periods of
+// at least opts.MinStayDuration within opts.StayRadius, and trips between
+// them. Both are returned in chronological order.
+func Segment(positions []tractive.TrackerPosition, opts SegmentOptions) ([]*Stay, []*Trip) {
+	var (
+		stays []*Stay
+		trips []*Trip
+		// tripStart is the index of the first position of the current
+		// trip, i.e. the last position of the previous stay.
+		tripStart int
+	)
+	addTrip := func(from, to int, toStay *Stay) {
+		if to-from < 1 {
+			return
+		}
+		var fromStay *Stay
+		if len(stays) > 0 {
+			fromStay = stays[len(stays)-1]
+		}
+		trips = append(trips, newTrip(positions[from:to+1], fromStay, toStay))
+	}
+	for i := 0; i < len(positions); {
+		j := i + 1
+		for j < len(positions) && Distance(positions[i], positions[j]) <= opts.StayRadius {
+			j++
+		}
+		if time.Duration(positions[j-1].Time-positions[i].Time)*time.Second < opts.MinStayDuration {
+			i++
+			continue
+		}
+		stay := newStay(positions[i:j])
+		addTrip(tripStart, i, stay)
+		stays = append(stays, stay)
+		tripStart = j - 1
+		i = j
+	}
+	addTrip(tripStart, len(positions)-1, nil)
+	return stays, trips
+}
+
+func newStay(positions []tractive.TrackerPosition) *Stay {
+	s := Stay{
+		Start:     time.Unix(positions[0].Time, 0),
+		End:       time.Unix(positions[len(positions)-1].Time, 0),
+		Positions: len(positions),
+	}
+	for _, p := range positions {
+		s.Lat += p.LatLong[0]
+		s.Lon += p.LatLong[1]
+	}
+	s.Lat /= float64(len(positions))
+	s.Lon /= float64(len(positions))
+	return &s
+}
+
+func newTrip(positions []tractive.TrackerPosition, from, to *Stay) *Trip {
+	t := Trip{
+		Start:     time.Unix(positions[0].Time, 0),
+		End:       time.Unix(positions[len(positions)-1].Time, 0),
+		From:      from,
+		To:        to,
+		Positions: len(positions),
+	}
+	for i := 1; i < len(positions); i++ {
+		d := Distance(positions[i-1], positions[i])
+		t.Distance += d
+		if dt := positions[i].Time - positions[i-1].Time; dt > 0 {
+			if speed := d / float64(dt); speed > t.MaxSpeed {
+				t.MaxSpeed = speed
+			}
+		}
+	}
+	if secs := t.Duration().Seconds(); secs > 0 {
+		t.AvgSpeed = t.Distance / secs
+	}
+	return &t
+}
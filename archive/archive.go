@@ -0,0 +1,242 @@
+// Package archive stores tracker positions in a local SQLite database, so
+// that the history can be queried without hitting the Tractive API, and
+// kept up to date incrementally.
+package archive
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/insomniacslk/tractive"
+	// pure-Go SQLite driver, registered as "sqlite".
+	_ "modernc.org/sqlite"
+)
+
+const schema = `
+CREATE TABLE IF NOT EXISTS positions (
+	tracker_id      TEXT    NOT NULL,
+	time            INTEGER NOT NULL,
+	lat             REAL    NOT NULL,
+	lon             REAL    NOT NULL,
+	alt             INTEGER NOT NULL,
+	speed           REAL    NOT NULL,
+	course          INTEGER NOT NULL,
+	pos_uncertainty INTEGER NOT NULL,
+	sensor_used     TEXT    NOT NULL,
+	PRIMARY KEY (tracker_id, time)
+) WITHOUT ROWID;
+CREATE INDEX IF NOT EXISTS positions_lat_lon ON positions (lat, lon);
+`
+
+// Archive is a local store of tracker positions. Positions are keyed by
+// tracker and time: storing a position again for the same tracker and time
+// is a no-op.
+type Archive struct {
+	db *sql.DB
+}
+
+// Fix is a stored position of a tracker.
+type Fix struct {
+	TrackerID string
+	tractive.TrackerPosition
+}
+
+// BoundingBox is a geographic area delimited by two parallels and two
+// meridians.
+type BoundingBox struct {
+	MinLat, MinLon float64
+	MaxLat, MaxLon float64
+}
+
+// Query selects stored positions. Zero-valued fields don't restrict the
+// selection.
+type Query struct {
+	TrackerID   string
+	Start       time.Time
+	End         time.Time
+	BoundingBox *BoundingBox
+}
+
+// DefaultPath returns the path of the archive in the user's data directory,
+// e.g. $XDG_DATA_HOME/tractive/archive.db .
+func DefaultPath() (string, error) {
+	dir := os.Getenv("XDG_DATA_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to get home dir: %w", err)
+		}
+		dir = filepath.Join(home, ".local", "share")
+	}
+	return filepath.Join(dir, "tractive", "archive.db"), nil
+}
+
+// Open opens the archive at the given path, creating it if needed.
+func Open(path string) (*Archive, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create archive directory: %w", err)
+	}
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open archive: %w", err)
+	}
+	// SQLite doesn't support concurrent writers.
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create archive schema: %w", err)
+	}
+	return &Archive{db: db}, nil
+}
+
+func (a *Archive) Close() error {
+	return a.db.Close()
+}
+
+// Insert stores the positions of a tracker, and returns how many of them
+// were not already stored.
+func (a *Archive) Insert(ctx context.Context, trackerID string, positions []tractive.TrackerPosition) (int, error) {
+	tx, err := a.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	stmt, err := tx.PrepareContext(ctx, `INSERT OR IGNORE INTO positions
+		(tracker_id, time, lat, lon, alt, speed, course, pos_uncertainty, sensor_used)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer stmt.Close()
+	var inserted int
+	for _, p := range positions {
+		res, err := stmt.ExecContext(ctx, trackerID, p.Time, p.LatLong[0], p.LatLong[1], p.Alt, p.Speed, p.Course, p.PosUncertainty, p.SensorUsed)
+		if err != nil {
+			return 0, fmt.Errorf("failed to insert position: %w", err)
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return 0, fmt.Errorf("failed to get affected rows: %w", err)
+		}
+		inserted += int(n)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return inserted, nil
+}
+
+// LastFix returns the most recent stored position of a tracker, if any.
+func (a *Archive) LastFix(ctx context.Context, trackerID string) (*Fix, error) {
+	fixes, err := a.query(ctx, Query{TrackerID: trackerID}, "ORDER BY time DESC LIMIT 1")
+	if err != nil {
+		return nil, err
+	}
+	if len(fixes) == 0 {
+		return nil, nil
+	}
+	return &fixes[0], nil
+}
+
+// Trackers returns the IDs of the trackers having stored positions.
+func (a *Archive) Trackers(ctx context.Context) ([]string, error) {
+	rows, err := a.db.QueryContext(ctx, "SELECT DISTINCT tracker_id FROM positions ORDER BY tracker_id")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query trackers: %w", err)
+	}
+	defer rows.Close()
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan tracker: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// Positions returns the stored positions selected by the query, ordered by
+// tracker and time.
+func (a *Archive) Positions(ctx context.Context, q Query) ([]Fix, error) {
+	return a.query(ctx, q, "ORDER BY tracker_id, time")
+}
+
+func (a *Archive) query(ctx context.Context, q Query, suffix string) ([]Fix, error) {
+	var (
+		where []string
+		args  []interface{}
+	)
+	if q.TrackerID != "" {
+		where = append(where, "tracker_id = ?")
+		args = append(args, q.TrackerID)
+	}
+	if !q.Start.IsZero() {
+		where = append(where, "time >= ?")
+		args = append(args, q.Start.Unix())
+	}
+	if !q.End.IsZero() {
+		where = append(where, "time <= ?")
+		args = append(args, q.End.Unix())
+	}
+	if b := q.BoundingBox; b != nil {
+		where = append(where, "lat BETWEEN ? AND ?", "lon BETWEEN ? AND ?")
+		args = append(args, b.MinLat, b.MaxLat, b.MinLon, b.MaxLon)
+	}
+	query := "SELECT tracker_id, time, lat, lon, alt, speed, course, pos_uncertainty, sensor_used FROM positions"
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	rows, err := a.db.QueryContext(ctx, query+" "+suffix, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query positions: %w", err)
+	}
+	defer rows.Close()
+	var fixes []Fix
+	for rows.Next() {
+		var f Fix
+		if err := rows.Scan(&f.TrackerID, &f.Time, &f.LatLong[0], &f.LatLong[1], &f.Alt, &f.Speed, &f.Course, &f.PosUncertainty, &f.SensorUsed); err != nil {
+			return nil, fmt.Errorf("failed to scan position: %w", err)
+		}
+		fixes = append(fixes, f)
+	}
+	return fixes, rows.Err()
+}
+
+// Sync downloads and stores the positions of a tracker that are more recent
+// than its last stored one, or than `since` if none is stored, up to now.
+// It returns the number of new positions.
+func (a *Archive) Sync(ctx context.Context, t *tractive.Tractive, trackerID string, since time.Time) (int, error) {
+	last, err := a.LastFix(ctx, trackerID)
+	if err != nil {
+		return 0, err
+	}
+	start := since
+	if last != nil {
+		start = time.Unix(last.Time+1, 0)
+	}
+	end := time.Now()
+	if !start.Before(end) {
+		return 0, nil
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	f := tractive.HistoryFetcher{Tractive: t}
+	var total int
+	for chunk := range f.Fetch(ctx, trackerID, start, end) {
+		if chunk.Err != nil {
+			return total, fmt.Errorf("failed to get positions from %s to %s: %w", chunk.Start, chunk.End, chunk.Err)
+		}
+		n, err := a.Insert(ctx, trackerID, chunk.History.All())
+		if err != nil {
+			return total, err
+		}
+		total += n
+	}
+	return total, ctx.Err()
+}
@@ -1,14 +1,21 @@
 package tractive
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"net/url"
+	"net/http"
 	"time"
 
 	"github.com/insomniacslk/xjson"
 )
 
+type authenticationRequest struct {
+	GrantType     string `json:"grant_type"`
+	PlatformEmail string `json:"platform_email"`
+	PlatformToken string `json:"platform_token"`
+}
+
 type AuthenticationResponse struct {
 	UserID      string         `json:"user_id"`
 	ClientID    string         `json:"client_id"`
@@ -16,15 +23,45 @@ type AuthenticationResponse struct {
 	AccessToken string         `json:"access_token"`
 }
 
-func Authenticate(username, password string) (*Tractive, error) {
-	u := getTractiveURL()
-	u.Path = "/4/auth/token"
-	v := url.Values{}
-	v.Set("grant_type", "tractive")
-	v.Set("platform_email", username)
-	v.Set("platform_token", password)
-	u.RawQuery = v.Encode()
-	body, err := tractiveRequest("POST", u, "")
+// Authenticate is like AuthenticateContext, with a background context.
+func Authenticate(username, password string, opts ...Option) (*Tractive, error) {
+	return AuthenticateContext(context.Background(), username, password, opts...)
+}
+
+// AuthenticateContext returns a client configured with the given options and
+// logged into Tractive. The credentials are kept in the client, which uses
+// them to re-authenticate when the token expires. If a token store is
+// configured and holds a valid session for the same user, it is resumed
+// instead of logging in again.
+func AuthenticateContext(ctx context.Context, username, password string, opts ...Option) (*Tractive, error) {
+	t := New(append(opts, WithCredentials(username, password))...)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.Token != "" {
+		return t, nil
+	}
+	ar, err := t.login(ctx)
+	if err != nil {
+		return nil, err
+	}
+	t.setSession(ar)
+	t.saveSession()
+	return t, nil
+}
+
+// login obtains a new token with the stored credentials, without changing
+// the session of the client.
+func (t *Tractive) login(ctx context.Context) (*AuthenticationResponse, error) {
+	u := t.tractiveURL("/4/auth/token")
+	reqBody, err := json.Marshal(authenticationRequest{
+		GrantType:     "tractive",
+		PlatformEmail: t.Username,
+		PlatformToken: t.Password,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal json request: %w", err)
+	}
+	body, err := t.do(ctx, http.MethodPost, u, "", reqBody)
 	if err != nil {
 		return nil, fmt.Errorf("http request failed: %w", err)
 	}
@@ -32,12 +69,19 @@ func Authenticate(username, password string) (*Tractive, error) {
 	if err := json.Unmarshal(body, &ar); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal json response: %w", err)
 	}
-	return &Tractive{
-		Username:       username,
-		Password:       password,
-		UserID:         ar.UserID,
-		ClientID:       ar.ClientID,
-		Token:          ar.AccessToken,
-		TokenExpiresAt: time.Unix(time.Time(ar.ExpiresAt).Unix(), 0),
-	}, nil
+	return &ar, nil
+}
+
+func (t *Tractive) setSession(ar *AuthenticationResponse) {
+	t.UserID = ar.UserID
+	t.ClientID = ar.ClientID
+	t.setToken(ar)
+}
+
+// setToken updates the token only. Unlike the rest of the session, it may
+// change while other requests are in flight, so it must be called with t.mu
+// held.
+func (t *Tractive) setToken(ar *AuthenticationResponse) {
+	t.Token = ar.AccessToken
+	t.TokenExpiresAt = time.Unix(time.Time(ar.ExpiresAt).Unix(), 0)
 }
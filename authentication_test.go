@@ -0,0 +1,94 @@
+package tractive
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeAPI is a fake Tractive API that authenticates any user as U1, with
+// tokens named renewed-1, renewed-2 and so on, and serves the account info
+// of U1 to the holders of such a token.
+type fakeAPI struct {
+	*httptest.Server
+	logins atomic.Int32
+	// clientID records the X-Tractive-Client header of the last request.
+	clientID atomic.Value
+}
+
+func newFakeAPI(t *testing.T) *fakeAPI {
+	f := fakeAPI{}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.clientID.Store(r.Header.Get("X-Tractive-Client"))
+		switch r.URL.Path {
+		case "/4/auth/token":
+			// slow down logins, so that concurrent callers overlap.
+			time.Sleep(50 * time.Millisecond)
+			n := f.logins.Add(1)
+			fmt.Fprintf(w, `{"user_id":"U1","client_id":"issued","access_token":"renewed-%d","expires_at":%d}`, n, time.Now().Add(time.Hour).Unix())
+		case "/4/user/U1":
+			if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer renewed-") {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprint(w, `{"_id":"U1","email":"user@example.com"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(f.Close)
+	return &f
+}
+
+func (f *fakeAPI) baseURL() *url.URL {
+	u, _ := url.Parse(f.URL)
+	return u
+}
+
+func TestClientIDSurvivesLogin(t *testing.T) {
+	f := newFakeAPI(t)
+	tr, err := Authenticate("user@example.com", "secret", WithBaseURL(f.baseURL()), WithClientID("configured"), WithLogger(testLogger{t}))
+	if err != nil {
+		t.Fatalf("Authenticate failed: %v", err)
+	}
+	if tr.ClientID != "issued" {
+		t.Errorf("got session client ID %q, want issued", tr.ClientID)
+	}
+	if _, err := tr.GetAccountInfo(); err != nil {
+		t.Fatalf("GetAccountInfo failed: %v", err)
+	}
+	if got := f.clientID.Load(); got != "configured" {
+		t.Errorf("got X-Tractive-Client %q, want configured", got)
+	}
+}
+
+func TestConcurrentCallersShareRenewal(t *testing.T) {
+	f := newFakeAPI(t)
+	tr := New(WithBaseURL(f.baseURL()), WithToken("U1", "expired"), WithCredentials("user@example.com", "secret"), WithLogger(testLogger{t}))
+	tr.TokenExpiresAt = time.Now().Add(-time.Hour)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := tr.GetAccountInfoContext(context.Background()); err != nil {
+				t.Errorf("GetAccountInfoContext failed: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := f.logins.Load(); n != 1 {
+		t.Errorf("got %d logins, want 1", n)
+	}
+	if s := tr.Session(); s.Token != "renewed-1" || s.UserID != "U1" {
+		t.Errorf("got session %+v, want token renewed-1 for U1", s)
+	}
+}
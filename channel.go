@@ -0,0 +1,316 @@
+package tractive
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// ChannelHost is the host of the Tractive event stream.
+const ChannelHost = "channel.tractive.com"
+
+// DefaultKeepAliveTimeout is how long Subscribe waits for a message before
+// considering the connection dead, until the server announces its keep-alive
+// interval.
+const DefaultKeepAliveTimeout = 30 * time.Second
+
+// keepAliveMisses is how many keep-alive intervals may pass without messages
+// before the connection is considered dead.
+const keepAliveMisses = 3
+
+var (
+	errChannelClosed   = errors.New("event channel closed by the server")
+	errKeepAliveMissed = errors.New("no keep-alive received")
+)
+
+// WithChannelURL sets the URL of the event stream used by Subscribe. The
+// default is https://channel.tractive.com/3/channel .
+func WithChannelURL(u *url.URL) Option {
+	return func(t *Tractive) {
+		t.channelURL = u
+	}
+}
+
+func (t *Tractive) getChannelURL() url.URL {
+	if t.channelURL != nil {
+		return *t.channelURL
+	}
+	return url.URL{
+		Scheme: TractiveScheme,
+		Host:   ChannelHost,
+		Path:   "/3/channel",
+	}
+}
+
+// Event is a message received from the event stream. It is one of
+// *PositionEvent, *HardwareEvent, *TrackerStatusEvent or *ErrorEvent.
+type Event interface {
+	isEvent()
+}
+
+// PositionEvent is a new position of a tracker.
+type PositionEvent struct {
+	TrackerID string
+	Position  TrackerPosition
+}
+
+// HardwareEvent is a new hardware report of a tracker.
+type HardwareEvent struct {
+	TrackerID string
+	Hardware  GetTrackerHardwareResponse
+}
+
+// TrackerStatusEvent is a change of state of a tracker.
+type TrackerStatusEvent struct {
+	TrackerID string
+	// State is the state of the tracker, e.g. OPERATIONAL or
+	// NOT_REPORTING.
+	State        string
+	StateReason  string
+	LiveTracking bool
+}
+
+// ErrorEvent is the error that ended a subscription. It is the last event
+// before the channel is closed.
+type ErrorEvent struct {
+	Err error
+}
+
+func (*PositionEvent) isEvent()      {}
+func (*HardwareEvent) isEvent()      {}
+func (*TrackerStatusEvent) isEvent() {}
+func (*ErrorEvent) isEvent()         {}
+
+// channelMessage is a message of the event stream.
+type channelMessage struct {
+	Message string `json:"message"`
+	// KeepAliveTTL is the interval of the keep-alive messages in
+	// seconds, sent with the handshake.
+	KeepAliveTTL       int                         `json:"keep_alive_ttl"`
+	TrackerID          string                      `json:"tracker_id"`
+	TrackerState       string                      `json:"tracker_state"`
+	TrackerStateReason string                      `json:"tracker_state_reason"`
+	BatteryState       string                      `json:"battery_state"`
+	ChargingState      string                      `json:"charging_state"`
+	Position           *channelPosition            `json:"position"`
+	Hardware           *GetTrackerHardwareResponse `json:"hardware"`
+	LiveTracking       *struct {
+		Active bool `json:"active"`
+	} `json:"live_tracking"`
+}
+
+// channelPosition is a position in the event stream, which reports the
+// uncertainty as accuracy.
+type channelPosition struct {
+	TrackerPosition
+}
+
+func (p *channelPosition) UnmarshalJSON(b []byte) error {
+	if err := json.Unmarshal(b, &p.TrackerPosition); err != nil {
+		return err
+	}
+	var v struct {
+		Accuracy *int `json:"accuracy"`
+	}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	if v.Accuracy != nil && p.PosUncertainty == 0 {
+		p.PosUncertainty = *v.Accuracy
+	}
+	return nil
+}
+
+// events returns the events carried by a tracker_status message.
+func (m *channelMessage) events() []Event {
+	var events []Event
+	if m.TrackerState != "" || m.LiveTracking != nil {
+		e := &TrackerStatusEvent{
+			TrackerID:   m.TrackerID,
+			State:       m.TrackerState,
+			StateReason: m.TrackerStateReason,
+		}
+		if m.LiveTracking != nil {
+			e.LiveTracking = m.LiveTracking.Active
+		}
+		events = append(events, e)
+	}
+	if m.Hardware != nil {
+		hw := *m.Hardware
+		if hw.BatteryState == "" {
+			hw.BatteryState = m.BatteryState
+		}
+		if hw.ChargingState == "" {
+			hw.ChargingState = m.ChargingState
+		}
+		events = append(events, &HardwareEvent{TrackerID: m.TrackerID, Hardware: hw})
+	}
+	if m.Position != nil {
+		events = append(events, &PositionEvent{TrackerID: m.TrackerID, Position: m.Position.TrackerPosition})
+	}
+	return events
+}
+
+// Subscribe opens the event stream of the account and delivers its events on
+// the returned channel. Dropped connections, and connections that stop
+// receiving keep-alives, are reopened with the backoff of the retry policy,
+// renewing the token if needed. The channel is closed when ctx is done, or
+// after an *ErrorEvent if the stream can't be reopened because the client is
+// not authorized.
+func (t *Tractive) Subscribe(ctx context.Context) <-chan Event {
+	events := make(chan Event)
+	go func() {
+		defer close(events)
+		if err := t.subscribe(ctx, events); err != nil && ctx.Err() == nil {
+			select {
+			case events <- &ErrorEvent{Err: err}:
+			case <-ctx.Done():
+			}
+		}
+	}()
+	return events
+}
+
+func (t *Tractive) subscribe(ctx context.Context, events chan<- Event) error {
+	policy := DefaultRetryPolicy
+	if t.RetryPolicy != nil {
+		policy = *t.RetryPolicy
+	}
+	renewed := false
+	for attempt := 0; ; attempt++ {
+		token, err := t.currentToken(ctx)
+		if err != nil {
+			return err
+		}
+		if err := t.RateLimiter.Wait(ctx); err != nil {
+			return err
+		}
+		received, err := t.listen(ctx, token, events)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if received {
+			attempt, renewed = 0, false
+		}
+		if errors.Is(err, ErrUnauthorized) && t.hasCredentials() && !renewed {
+			if _, err := t.renewToken(ctx, token); err != nil {
+				return err
+			}
+			renewed = true
+			continue
+		}
+		if errors.Is(err, ErrUnauthorized) || errors.Is(err, ErrForbidden) {
+			return err
+		}
+		var retryAfter time.Duration
+		var apiErr *APIError
+		if errors.As(err, &apiErr) {
+			retryAfter = apiErr.RetryAfter
+		}
+		wait := policy.backoff(attempt, retryAfter)
+		t.getLogger().Warningf("Event channel disconnected: %v. Reconnecting in %s", err, wait)
+		if err := sleepContext(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
+
+// listen reads the event stream until the connection fails, and tells
+// whether any message was received.
+func (t *Tractive) listen(ctx context.Context, token string, events chan<- Event) (bool, error) {
+	connCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	u := t.getChannelURL()
+	req, err := http.NewRequestWithContext(connCtx, http.MethodPost, u.String(), nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to create http request: %w", err)
+	}
+	req.Header.Set("X-Tractive-Client", t.getClientID())
+	if t.UserID != "" {
+		req.Header.Set("X-Tractive-User", t.UserID)
+	}
+	if t.userAgent != "" {
+		req.Header.Set("User-Agent", t.userAgent)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if t.debugEnabled() {
+		t.traceRequest(req, nil)
+	}
+
+	// the stream never ends, so it must not be subject to the timeout of
+	// the HTTP client.
+	client := &http.Client{Transport: t.getHTTPClient().Transport}
+	resp, err := client.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("failed to execute http request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return false, fmt.Errorf("failed to get http body: %w", err)
+		}
+		if t.debugEnabled() {
+			t.traceResponse(resp, body)
+		}
+		return false, newAPIError(resp, body)
+	}
+
+	timeout := DefaultKeepAliveTimeout
+	watchdog := time.AfterFunc(timeout, cancel)
+	defer watchdog.Stop()
+	dec := json.NewDecoder(resp.Body)
+	received := false
+	for {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			switch {
+			case ctx.Err() != nil:
+				return received, ctx.Err()
+			case connCtx.Err() != nil:
+				return received, errKeepAliveMissed
+			case errors.Is(err, io.EOF):
+				return received, errChannelClosed
+			}
+			return received, fmt.Errorf("failed to read event channel: %w", err)
+		}
+		received = true
+		watchdog.Reset(timeout)
+		if t.debugEnabled() {
+			t.getLogger().Debugf("EVENT: %s", t.redactBody(raw))
+		}
+		var m channelMessage
+		if err := json.Unmarshal(raw, &m); err != nil {
+			t.getLogger().Warningf("Failed to decode event: %v", err)
+			continue
+		}
+		switch m.Message {
+		case "handshake":
+			if m.KeepAliveTTL > 0 {
+				timeout = keepAliveMisses * time.Duration(m.KeepAliveTTL) * time.Second
+				watchdog.Reset(timeout)
+			}
+		case "keep-alive":
+		case "tracker_status":
+			// a slow consumer must not look like a dead connection.
+			watchdog.Stop()
+			for _, e := range m.events() {
+				select {
+				case events <- e:
+				case <-ctx.Done():
+					return received, ctx.Err()
+				}
+			}
+			watchdog.Reset(timeout)
+		default:
+			t.getLogger().Debugf("Ignoring event %q", m.Message)
+		}
+	}
+}
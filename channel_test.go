@@ -0,0 +1,320 @@
+package tractive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const testTrackerStatus = `{"message":"tracker_status","tracker_id":"T1","tracker_state":"OPERATIONAL",` +
+	`"live_tracking":{"active":true},"charging_state":"NOT_CHARGING",` +
+	`"hardware":{"time":1700000000,"battery_level":80},` +
+	`"position":{"time":1700000001,"latlong":[48.2,16.3],"accuracy":12,"altitude":180,"sensor_used":"GPS"}}`
+
+type testLogger struct {
+	t *testing.T
+}
+
+func (l testLogger) Debugf(format string, args ...interface{})   { l.t.Logf(format, args...) }
+func (l testLogger) Infof(format string, args ...interface{})    { l.t.Logf(format, args...) }
+func (l testLogger) Warningf(format string, args ...interface{}) { l.t.Logf(format, args...) }
+
+// fakeStream is a fake Tractive API serving the event stream on
+// /3/channel and the authentication on /4/auth/token.
+type fakeStream struct {
+	*httptest.Server
+	logins atomic.Int32
+
+	mu sync.Mutex
+	// conns records the time and the token of every stream request.
+	conns  []time.Time
+	tokens []string
+}
+
+// newFakeStream starts a fake API whose stream requests are handled by
+// stream, which gets the number of the connection, starting at 1.
+func newFakeStream(t *testing.T, stream func(w http.ResponseWriter, r *http.Request, conn int)) *fakeStream {
+	f := fakeStream{}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/4/auth/token":
+			n := f.logins.Add(1)
+			fmt.Fprintf(w, `{"user_id":"U1","access_token":"renewed-%d","expires_at":%d}`, n, time.Now().Add(time.Hour).Unix())
+		case "/3/channel":
+			if r.Method != http.MethodPost {
+				t.Errorf("got method %s, want POST", r.Method)
+			}
+			f.mu.Lock()
+			f.conns = append(f.conns, time.Now())
+			f.tokens = append(f.tokens, r.Header.Get("Authorization"))
+			n := len(f.conns)
+			f.mu.Unlock()
+			stream(w, r, n)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(f.Close)
+	return &f
+}
+
+func (f *fakeStream) connections() ([]time.Time, []string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]time.Time(nil), f.conns...), append([]string(nil), f.tokens...)
+}
+
+// subscribe subscribes to the fake stream, and cancels the subscription and
+// waits for it to end when the test finishes.
+func (f *fakeStream) subscribe(t *testing.T, opts ...Option) <-chan Event {
+	base, _ := url.Parse(f.URL)
+	channel, _ := url.Parse(f.URL + "/3/channel")
+	opts = append([]Option{
+		WithBaseURL(base),
+		WithChannelURL(channel),
+		WithToken("U1", "initial"),
+		WithLogger(testLogger{t}),
+		WithRetryPolicy(RetryPolicy{MaxRetries: 3, MinBackoff: 50 * time.Millisecond, MaxBackoff: 50 * time.Millisecond}),
+	}, opts...)
+	ctx, cancel := context.WithCancel(context.Background())
+	events := New(opts...).Subscribe(ctx)
+	out := make(chan Event)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer close(out)
+		for e := range events {
+			select {
+			case out <- e:
+			case <-ctx.Done():
+			}
+		}
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return out
+}
+
+// writeLine writes a message of the stream and flushes it.
+func writeLine(w http.ResponseWriter, msg string) {
+	fmt.Fprintln(w, msg)
+	w.(http.Flusher).Flush()
+}
+
+func nextEvent(t *testing.T, events <-chan Event, timeout time.Duration) Event {
+	t.Helper()
+	select {
+	case e, ok := <-events:
+		if !ok {
+			t.Fatalf("event channel closed")
+		}
+		return e
+	case <-time.After(timeout):
+		t.Fatalf("no event received in %s", timeout)
+	}
+	return nil
+}
+
+func TestSubscribeEvents(t *testing.T) {
+	f := newFakeStream(t, func(w http.ResponseWriter, r *http.Request, conn int) {
+		if got := r.Header.Get("X-Tractive-User"); got != "U1" {
+			t.Errorf("got X-Tractive-User %q, want U1", got)
+		}
+		if got := r.Header.Get("X-Tractive-Client"); got != ClientID {
+			t.Errorf("got X-Tractive-Client %q, want %s", got, ClientID)
+		}
+		writeLine(w, `{"message":"handshake","keep_alive_ttl":10}`)
+		writeLine(w, `{"message":"keep-alive"}`)
+		writeLine(w, `{"message":"unknown"}`)
+		writeLine(w, testTrackerStatus)
+		<-r.Context().Done()
+	})
+	events := f.subscribe(t)
+
+	status, ok := nextEvent(t, events, 5*time.Second).(*TrackerStatusEvent)
+	if !ok {
+		t.Fatalf("first event is not a *TrackerStatusEvent")
+	}
+	if *status != (TrackerStatusEvent{TrackerID: "T1", State: "OPERATIONAL", LiveTracking: true}) {
+		t.Errorf("got status %+v", status)
+	}
+	hw, ok := nextEvent(t, events, 5*time.Second).(*HardwareEvent)
+	if !ok {
+		t.Fatalf("second event is not a *HardwareEvent")
+	}
+	if hw.TrackerID != "T1" || hw.Hardware.BatteryLevel != 80 || hw.Hardware.ChargingState != "NOT_CHARGING" ||
+		!time.Time(hw.Hardware.Time).Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("got hardware %+v", hw)
+	}
+	pos, ok := nextEvent(t, events, 5*time.Second).(*PositionEvent)
+	if !ok {
+		t.Fatalf("third event is not a *PositionEvent")
+	}
+	want := TrackerPosition{Time: 1700000001, LatLong: [2]float64{48.2, 16.3}, Alt: 180, PosUncertainty: 12, SensorUsed: "GPS"}
+	if pos.TrackerID != "T1" || pos.Position != want {
+		t.Errorf("got position %+v, want %+v", pos.Position, want)
+	}
+	if _, tokens := f.connections(); len(tokens) != 1 || tokens[0] != "Bearer initial" {
+		t.Errorf("got connections with tokens %v, want one with the initial token", tokens)
+	}
+}
+
+func TestSubscribeKeepAlive(t *testing.T) {
+	t.Parallel()
+	f := newFakeStream(t, func(w http.ResponseWriter, r *http.Request, conn int) {
+		// a TTL of one second gives up after three seconds without
+		// messages, instead of DefaultKeepAliveTimeout.
+		writeLine(w, `{"message":"handshake","keep_alive_ttl":1}`)
+		if conn == 1 {
+			// keep-alives hold the connection open past the timeout.
+			for i := 0; i < 8; i++ {
+				select {
+				case <-time.After(500 * time.Millisecond):
+					writeLine(w, `{"message":"keep-alive"}`)
+				case <-r.Context().Done():
+					return
+				}
+			}
+		}
+		writeLine(w, testTrackerStatus)
+		// then they stop, and the client must reconnect.
+		<-r.Context().Done()
+	})
+	events := f.subscribe(t)
+
+	nextEvent(t, events, 10*time.Second)
+	last := time.Now()
+	if conns, _ := f.connections(); len(conns) != 1 {
+		t.Fatalf("got %d connections while keep-alives were received, want 1", len(conns))
+	}
+	for {
+		e := nextEvent(t, events, 10*time.Second)
+		if conns, _ := f.connections(); len(conns) == 2 {
+			if _, ok := e.(*TrackerStatusEvent); !ok {
+				t.Fatalf("got %T after reconnecting, want *TrackerStatusEvent", e)
+			}
+			break
+		}
+	}
+	conns, _ := f.connections()
+	if d := conns[1].Sub(last); d < 2*time.Second || d > 5*time.Second {
+		t.Errorf("reconnected %s after the last message, want about keepAliveMisses times the TTL", d)
+	}
+}
+
+func TestSubscribeReconnectAfterEOF(t *testing.T) {
+	f := newFakeStream(t, func(w http.ResponseWriter, r *http.Request, conn int) {
+		writeLine(w, `{"message":"handshake","keep_alive_ttl":10}`)
+		if conn < 3 {
+			// close the stream.
+			return
+		}
+		writeLine(w, testTrackerStatus)
+		<-r.Context().Done()
+	})
+	events := f.subscribe(t)
+
+	if _, ok := nextEvent(t, events, 5*time.Second).(*TrackerStatusEvent); !ok {
+		t.Fatalf("first event is not a *TrackerStatusEvent")
+	}
+	conns, _ := f.connections()
+	if len(conns) != 3 {
+		t.Fatalf("got %d connections, want 3", len(conns))
+	}
+	// the backoff gets a jitter of up to half of its value.
+	for i := 1; i < len(conns); i++ {
+		if d := conns[i].Sub(conns[i-1]); d < 25*time.Millisecond {
+			t.Errorf("reconnection %d after %s, want at least 25ms of backoff", i, d)
+		}
+	}
+}
+
+func TestSubscribeRenewsToken(t *testing.T) {
+	f := newFakeStream(t, func(w http.ResponseWriter, r *http.Request, conn int) {
+		if r.Header.Get("Authorization") == "Bearer initial" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"code":4002,"message":"token expired"}`)
+			return
+		}
+		writeLine(w, testTrackerStatus)
+		<-r.Context().Done()
+	})
+	events := f.subscribe(t, WithCredentials("user@example.com", "secret"))
+
+	if _, ok := nextEvent(t, events, 5*time.Second).(*TrackerStatusEvent); !ok {
+		t.Fatalf("first event is not a *TrackerStatusEvent")
+	}
+	if n := f.logins.Load(); n != 1 {
+		t.Errorf("got %d logins, want 1", n)
+	}
+	_, tokens := f.connections()
+	if len(tokens) != 2 || tokens[0] != "Bearer initial" || tokens[1] != "Bearer renewed-1" {
+		t.Errorf("got connections with tokens %v, want the initial then the renewed one", tokens)
+	}
+}
+
+func TestSubscribeForbidden(t *testing.T) {
+	f := newFakeStream(t, func(w http.ResponseWriter, r *http.Request, conn int) {
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, `{"code":4003,"message":"forbidden"}`)
+	})
+	events := f.subscribe(t, WithCredentials("user@example.com", "secret"))
+
+	e, ok := nextEvent(t, events, 5*time.Second).(*ErrorEvent)
+	if !ok {
+		t.Fatalf("first event is not an *ErrorEvent")
+	}
+	if !errors.Is(e.Err, ErrForbidden) {
+		t.Errorf("got error %v, want ErrForbidden", e.Err)
+	}
+	select {
+	case e, ok := <-events:
+		if ok {
+			t.Errorf("got %T after the error, want the channel to be closed", e)
+		}
+	case <-time.After(5 * time.Second):
+		t.Errorf("channel not closed after the error")
+	}
+	if conns, _ := f.connections(); len(conns) != 1 {
+		t.Errorf("got %d connections, want 1", len(conns))
+	}
+	if n := f.logins.Load(); n != 0 {
+		t.Errorf("got %d logins, want 0", n)
+	}
+}
+
+func TestSubscribeCancel(t *testing.T) {
+	f := newFakeStream(t, func(w http.ResponseWriter, r *http.Request, conn int) {
+		writeLine(w, `{"message":"handshake","keep_alive_ttl":10}`)
+		<-r.Context().Done()
+	})
+	base, _ := url.Parse(f.URL)
+	channel, _ := url.Parse(f.URL + "/3/channel")
+	tr := New(WithBaseURL(base), WithChannelURL(channel), WithToken("U1", "initial"), WithLogger(testLogger{t}))
+	ctx, cancel := context.WithCancel(context.Background())
+	events := tr.Subscribe(ctx)
+	for {
+		if conns, _ := f.connections(); len(conns) > 0 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	select {
+	case e, ok := <-events:
+		if ok {
+			t.Errorf("got %T after cancellation, want the channel to be closed", e)
+		}
+	case <-time.After(5 * time.Second):
+		t.Errorf("channel not closed after cancellation")
+	}
+}
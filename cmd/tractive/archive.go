@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/insomniacslk/tractive"
+	"github.com/insomniacslk/tractive/archive"
+	"github.com/spf13/pflag"
+)
+
+// parseBoundingBox parses a bounding box given as
+// "min_lat,min_lon,max_lat,max_lon".
+func parseBoundingBox(s string) (*archive.BoundingBox, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 4 {
+		return nil, fmt.Errorf("expected min_lat,min_lon,max_lat,max_lon")
+	}
+	var v [4]float64
+	for i, p := range parts {
+		f, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid coordinate %q: %w", p, err)
+		}
+		v[i] = f
+	}
+	return &archive.BoundingBox{MinLat: v[0], MinLon: v[1], MaxLat: v[2], MaxLon: v[3]}, nil
+}
+
+func archiveCommand(store tractive.TokenStore, args []string) {
+	if len(args) == 0 || (args[0] != "sync" && args[0] != "query") {
+		fmt.Fprintf(os.Stderr, "Usage: archive sync|query [flags]\n")
+		os.Exit(2)
+	}
+	fs := pflag.NewFlagSet("archive "+args[0], pflag.ExitOnError)
+	flagDB := fs.String("db", "", "Path of the archive database. If empty, tractive/archive.db in the user data directory")
+	flagTracker := fs.StringP("tracker", "T", "", "Tracker ID. If empty, all trackers")
+	flagSince := fs.String("since", "", "sync: start time of the first sync of a tracker, as RFC3339 or UNIX timestamp. If empty, 30 days ago")
+	flagFrom := fs.String("from", "", "query: start time, as RFC3339 or UNIX timestamp")
+	flagTo := fs.String("to", "", "query: end time, as RFC3339 or UNIX timestamp")
+	flagBBox := fs.String("bbox", "", "query: bounding box, as min_lat,min_lon,max_lat,max_lon")
+	_ = fs.Parse(args[1:])
+
+	path := *flagDB
+	if path == "" {
+		var err error
+		path, err = archive.DefaultPath()
+		if err != nil {
+			log.Fatalf("Failed to get archive path: %v", err)
+		}
+	}
+	a, err := archive.Open(path)
+	if err != nil {
+		log.Fatalf("Failed to open archive: %v", err)
+	}
+	defer a.Close()
+	ctx := context.Background()
+
+	switch args[0] {
+	case "sync":
+		since := time.Now().Add(-30 * 24 * time.Hour)
+		if *flagSince != "" {
+			since, err = parseTime(*flagSince)
+			if err != nil {
+				log.Fatalf("Invalid start time %q: %v", *flagSince, err)
+			}
+		}
+		t := newClient(store)
+		trackerIDs := []string{*flagTracker}
+		if *flagTracker == "" {
+			trackers, err := t.GetAllTrackersContext(ctx)
+			if err != nil {
+				log.Fatalf("Failed to get trackers: %v", err)
+			}
+			trackerIDs = trackerIDs[:0]
+			for _, tr := range *trackers {
+				trackerIDs = append(trackerIDs, tr.ID)
+			}
+		}
+		for _, trackerID := range trackerIDs {
+			n, err := a.Sync(ctx, t, trackerID, since)
+			if err != nil {
+				log.Fatalf("Failed to sync tracker %q after %d new positions: %v", trackerID, n, err)
+			}
+			fmt.Printf("Tracker %s: %d new positions\n", trackerID, n)
+		}
+	case "query":
+		q := archive.Query{TrackerID: *flagTracker}
+		if *flagFrom != "" {
+			if q.Start, err = parseTime(*flagFrom); err != nil {
+				log.Fatalf("Invalid start time %q: %v", *flagFrom, err)
+			}
+		}
+		if *flagTo != "" {
+			if q.End, err = parseTime(*flagTo); err != nil {
+				log.Fatalf("Invalid end time %q: %v", *flagTo, err)
+			}
+		}
+		if *flagBBox != "" {
+			if q.BoundingBox, err = parseBoundingBox(*flagBBox); err != nil {
+				log.Fatalf("Invalid bounding box %q: %v", *flagBBox, err)
+			}
+		}
+		fixes, err := a.Positions(ctx, q)
+		if err != nil {
+			log.Fatalf("Failed to query archive: %v", err)
+		}
+		for _, f := range fixes {
+			fmt.Printf("%s %s\n", f.TrackerID, f.TrackerPosition.String())
+		}
+	}
+}
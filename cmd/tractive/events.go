@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/insomniacslk/tractive"
+	"github.com/spf13/pflag"
+)
+
+func events(store tractive.TokenStore, args []string) {
+	fs := pflag.NewFlagSet("events", pflag.ExitOnError)
+	flagTracker := fs.StringP("tracker", "T", "", "Tracker ID. If empty, the events of all trackers")
+	_ = fs.Parse(args)
+	t := newClient(store)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	wanted := func(trackerID string) bool {
+		return *flagTracker == "" || trackerID == *flagTracker
+	}
+	for e := range t.Subscribe(ctx) {
+		switch e := e.(type) {
+		case *tractive.PositionEvent:
+			if wanted(e.TrackerID) {
+				fmt.Printf("%s position %s\n", e.TrackerID, e.Position.String())
+			}
+		case *tractive.HardwareEvent:
+			if wanted(e.TrackerID) {
+				fmt.Printf("%s hardware %s\n", e.TrackerID, e.Hardware.String())
+			}
+		case *tractive.TrackerStatusEvent:
+			if wanted(e.TrackerID) {
+				fmt.Printf("[%s] %s status state=%s reason=%s live_tracking=%t\n", time.Now().Format(time.RFC3339), e.TrackerID, e.State, e.StateReason, e.LiveTracking)
+			}
+		case *tractive.ErrorEvent:
+			log.Fatalf("Event stream failed: %v", e.Err)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/insomniacslk/tractive"
+	"github.com/insomniacslk/tractive/analysis"
+	"github.com/insomniacslk/tractive/export"
+	"github.com/spf13/pflag"
+)
+
+// parseTime parses a time given on the command line, either as RFC3339 or
+// as UNIX timestamp.
+func parseTime(s string) (time.Time, error) {
+	if ts, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(ts, 0), nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+// timeRange returns the time range selected by the --from and --to flags,
+// defaulting to the 24 hours before now.
+func timeRange(from, to string) (time.Time, time.Time) {
+	end := time.Now()
+	if to != "" {
+		var err error
+		end, err = parseTime(to)
+		if err != nil {
+			log.Fatalf("Invalid end time %q: %v", to, err)
+		}
+	}
+	start := end.Add(-24 * time.Hour)
+	if from != "" {
+		var err error
+		start, err = parseTime(from)
+		if err != nil {
+			log.Fatalf("Invalid start time %q: %v", from, err)
+		}
+	}
+	if !start.Before(end) {
+		log.Fatalf("Start time %s is not before end time %s", start, end)
+	}
+	return start, end
+}
+
+func exportPositions(store tractive.TokenStore, args []string) {
+	fs := pflag.NewFlagSet("export", pflag.ExitOnError)
+	flagFormat := fs.StringP("format", "f", "gpx", "Output format: gpx, geojson, kml, csv or tsv")
+	flagTracker := fs.StringP("tracker", "T", "", "Tracker ID. Can be empty for csv and tsv, to export all the trackers")
+	flagFrom := fs.String("from", "", "Start time, as RFC3339 or UNIX timestamp. If empty, 24 hours before the end time")
+	flagTo := fs.String("to", "", "End time, as RFC3339 or UNIX timestamp. If empty, now")
+	flagOutput := fs.StringP("output", "o", "-", "Output file, or - for the standard output")
+	flagPoints := fs.Bool("points", false, "Also export every position as a point (geojson and kml only)")
+	flagMaxUncertainty := fs.Int("max-uncertainty", 0, "Drop positions with a larger uncertainty, in meters. 0 to keep all")
+	flagMaxSpeed := fs.Float64("max-speed", 0, "Drop positions implying a larger speed, in km/h. 0 to keep all")
+	flagDedup := fs.Bool("dedup", false, "Drop consecutive positions at the same coordinates")
+	flagSmooth := fs.Float64("smooth", 0, "Smooth positions with a Kalman filter with this process noise, in m/s. 0 to disable")
+	_ = fs.Parse(args)
+	pipeline := analysis.NewPipeline(analysis.FilterOptions{
+		MaxUncertainty: *flagMaxUncertainty,
+		Dedup:          *flagDedup,
+		MaxSpeed:       *flagMaxSpeed / 3.6,
+		Smooth:         *flagSmooth,
+	})
+	tabular := *flagFormat == "csv" || *flagFormat == "tsv"
+	if *flagTracker == "" && !tabular {
+		log.Fatalf("No tracker specified")
+	}
+	start, end := timeRange(*flagFrom, *flagTo)
+	t := newClient(store)
+	ctx := context.Background()
+
+	pets, err := t.GetPetsByTrackerContext(ctx)
+	if err != nil {
+		log.Fatalf("Failed to get pets: %v", err)
+	}
+	petName := func(trackerID string) string {
+		if pet, ok := pets[trackerID]; ok {
+			return pet.Details.Name
+		}
+		return ""
+	}
+
+	var out io.Writer = os.Stdout
+	if *flagOutput != "-" {
+		fd, err := os.Create(*flagOutput)
+		if err != nil {
+			log.Fatalf("Failed to create output file: %v", err)
+		}
+		defer fd.Close()
+		out = fd
+	}
+	w := bufio.NewWriter(out)
+
+	if tabular {
+		trackerIDs := []string{*flagTracker}
+		if *flagTracker == "" {
+			trackers, err := t.GetAllTrackersContext(ctx)
+			if err != nil {
+				log.Fatalf("Failed to get trackers: %v", err)
+			}
+			trackerIDs = trackerIDs[:0]
+			for _, tr := range *trackers {
+				trackerIDs = append(trackerIDs, tr.ID)
+			}
+		}
+		comma := ','
+		if *flagFormat == "tsv" {
+			comma = '\t'
+		}
+		cw := export.NewCSVWriter(w, comma)
+		var count int
+		for _, trackerID := range trackerIDs {
+			if len(pipeline) > 0 {
+				// the filters keep state along a segment, so they
+				// need the whole history: filtering window by
+				// window would give results depending on the
+				// window size.
+				history, err := t.GetTrackerPositionHistoryContext(ctx, trackerID, start, end)
+				if err != nil {
+					log.Fatalf("Failed to get tracker %q 's positions: %v", trackerID, err)
+				}
+				positions := pipeline.ApplyHistory(history).All()
+				if err := cw.Write(trackerID, petName(trackerID), positions...); err != nil {
+					log.Fatalf("Failed to export positions: %v", err)
+				}
+				count += len(positions)
+				continue
+			}
+			// stream the history window by window, so that long
+			// ranges are not held in memory.
+			f := tractive.HistoryFetcher{Tractive: t}
+			for chunk := range f.Fetch(ctx, trackerID, start, end) {
+				if chunk.Err != nil {
+					log.Fatalf("Failed to get tracker %q 's positions from %s to %s: %v", trackerID, chunk.Start, chunk.End, chunk.Err)
+				}
+				positions := chunk.History.All()
+				if err := cw.Write(trackerID, petName(trackerID), positions...); err != nil {
+					log.Fatalf("Failed to export positions: %v", err)
+				}
+				count += len(positions)
+			}
+		}
+		if err := cw.Flush(); err != nil {
+			log.Fatalf("Failed to export positions: %v", err)
+		}
+		if err := w.Flush(); err != nil {
+			log.Fatalf("Failed to write output: %v", err)
+		}
+		fmt.Fprintf(os.Stderr, "Exported %d positions\n", count)
+		return
+	}
+
+	history, err := t.GetTrackerPositionHistoryContext(ctx, *flagTracker, start, end)
+	if err != nil {
+		log.Fatalf("Failed to get tracker %q 's positions: %v", *flagTracker, err)
+	}
+	history = pipeline.ApplyHistory(history)
+	name := petName(*flagTracker)
+	if name == "" {
+		name = *flagTracker
+	}
+	switch *flagFormat {
+	case "gpx":
+		err = export.WriteGPX(w, name, history)
+	case "geojson":
+		err = export.WriteGeoJSON(w, name, history, *flagPoints)
+	case "kml":
+		err = export.WriteKML(w, name, history, *flagPoints)
+	default:
+		log.Fatalf("Unsupported format %q", *flagFormat)
+	}
+	if err != nil {
+		log.Fatalf("Failed to export positions: %v", err)
+	}
+	if err := w.Flush(); err != nil {
+		log.Fatalf("Failed to write output: %v", err)
+	}
+	fmt.Fprintf(os.Stderr, "Exported %d positions\n", history.Len())
+}
@@ -0,0 +1,303 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/insomniacslk/tractive"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+// zonesFile is the file describing the desired geofences, e.g.:
+//
+//	pets:
+//	  - pet: Rex
+//	    geofences:
+//	      - name: Home
+//	        center: [48.2082, 16.3738]
+//	        radius: 100
+//	        alerts: {on_exit: true}
+//	      - name: Road
+//	        type: DANGER
+//	        polygon: [[48.20, 16.37], [48.21, 16.37], [48.21, 16.38]]
+//	        alerts: {on_enter: true}
+//
+// Every entry selects a tracker, either by ID with "tracker" or by the name
+// of its pet with "pet". The geofences of a selected tracker that are not in
+// the file are deleted; the trackers that are not selected are left alone.
+// An entry without geofences, which would delete all of them, is refused
+// unless --allow-delete-all is given.
+type zonesFile struct {
+	Pets []struct {
+		Pet       string `yaml:"pet"`
+		Tracker   string `yaml:"tracker"`
+		Geofences []zone `yaml:"geofences"`
+	} `yaml:"pets"`
+}
+
+type zone struct {
+	Name    string       `yaml:"name"`
+	Type    string       `yaml:"type"`
+	Center  *[2]float64  `yaml:"center"`
+	Radius  int          `yaml:"radius"`
+	Polygon [][2]float64 `yaml:"polygon"`
+	Active  *bool        `yaml:"active"`
+	Alerts  struct {
+		OnEnter bool `yaml:"on_enter"`
+		OnExit  bool `yaml:"on_exit"`
+	} `yaml:"alerts"`
+}
+
+// geofence returns the Tractive geofence described by the zone. Zones are
+// safe and active unless specified otherwise.
+func (z *zone) geofence() (*tractive.Geofence, error) {
+	g := tractive.Geofence{
+		Name:      z.Name,
+		FenceType: strings.ToUpper(z.Type),
+		Active:    z.Active == nil || *z.Active,
+		Alerts: tractive.GeofenceAlertSettings{
+			OnEnter: z.Alerts.OnEnter,
+			OnExit:  z.Alerts.OnExit,
+		},
+	}
+	if g.FenceType == "" {
+		g.FenceType = tractive.GeofenceTypeSafe
+	}
+	switch {
+	case z.Center != nil && z.Polygon != nil:
+		return nil, fmt.Errorf("zone %q has both a center and a polygon", z.Name)
+	case z.Center != nil:
+		g.Shape = tractive.GeofenceShapeCircle
+		g.Coords = [][2]float64{*z.Center}
+		g.Radius = z.Radius
+	default:
+		g.Shape = tractive.GeofenceShapePolygon
+		g.Coords = z.Polygon
+	}
+	if err := g.Validate(); err != nil {
+		return nil, err
+	}
+	return &g, nil
+}
+
+type operationKind string
+
+const (
+	opCreate operationKind = "create"
+	opUpdate operationKind = "update"
+	opDelete operationKind = "delete"
+	// opSkip is a change to a read-only geofence, which can't be applied.
+	opSkip operationKind = "skip"
+)
+
+// operation is a step of the plan turning the geofences on the account into
+// the desired ones.
+type operation struct {
+	kind      operationKind
+	trackerID string
+	current   *tractive.Geofence
+	desired   *tractive.Geofence
+}
+
+func (o *operation) String() string {
+	switch o.kind {
+	case opCreate:
+		return fmt.Sprintf("+ create %q: %s", o.desired.Name, describeGeofence(o.desired))
+	case opUpdate:
+		return fmt.Sprintf("~ update %q: %s -> %s", o.desired.Name, describeGeofence(o.current), describeGeofence(o.desired))
+	case opSkip:
+		return fmt.Sprintf("! skip %q: read-only, can't change %s -> %s", o.desired.Name, describeGeofence(o.current), describeGeofence(o.desired))
+	default:
+		return fmt.Sprintf("- delete %q: %s", o.current.Name, describeGeofence(o.current))
+	}
+}
+
+func describeGeofence(g *tractive.Geofence) string {
+	s := fmt.Sprintf("%s %s", g.FenceType, g.Shape)
+	if g.Shape == tractive.GeofenceShapeCircle && len(g.Coords) == 1 {
+		s += fmt.Sprintf(" center=%v radius=%dm", g.Coords[0], g.Radius)
+	} else {
+		s += fmt.Sprintf(" vertices=%v", g.Coords)
+	}
+	return s + fmt.Sprintf(" active=%t on_enter=%t on_exit=%t", g.Active, g.Alerts.OnEnter, g.Alerts.OnExit)
+}
+
+// sameGeofence tells whether two geofences have the same settings,
+// regardless of their ID and version.
+func sameGeofence(a, b *tractive.Geofence) bool {
+	return a.Name == b.Name &&
+		a.FenceType == b.FenceType &&
+		a.Shape == b.Shape &&
+		reflect.DeepEqual(a.Coords, b.Coords) &&
+		a.Radius == b.Radius &&
+		a.Active == b.Active &&
+		a.Alerts == b.Alerts
+}
+
+func geofencesCommand(store tractive.TokenStore, args []string) {
+	if len(args) == 0 || args[0] != "apply" {
+		fmt.Fprintf(os.Stderr, "Usage: geofences apply -f <zones.yaml> [--yes] [--allow-delete-all]\n")
+		os.Exit(2)
+	}
+	fs := pflag.NewFlagSet("geofences apply", pflag.ExitOnError)
+	flagFile := fs.StringP("file", "f", "", "YAML file with the desired geofences")
+	flagYes := fs.BoolP("yes", "y", false, "Apply the plan. If not set, the plan is only printed")
+	flagAllowDeleteAll := fs.Bool("allow-delete-all", false, "Allow entries without geofences, deleting all the geofences of their tracker")
+	_ = fs.Parse(args[1:])
+	if *flagFile == "" {
+		log.Fatalf("No file specified")
+	}
+	data, err := os.ReadFile(*flagFile)
+	if err != nil {
+		log.Fatalf("Failed to read zones file: %v", err)
+	}
+	// unknown keys are refused, so that a typo doesn't silently drop a
+	// setting, or the geofences of a tracker.
+	var zf zonesFile
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&zf); err != nil {
+		log.Fatalf("Failed to parse zones file: %v", err)
+	}
+
+	t := newClient(store)
+	ctx := context.Background()
+	plan, err := planGeofences(ctx, t, &zf, *flagAllowDeleteAll)
+	if err != nil {
+		log.Fatalf("Failed to plan geofence changes: %v", err)
+	}
+	counts := make(map[operationKind]int)
+	for _, op := range plan {
+		fmt.Printf("tracker %s: %s\n", op.trackerID, op.String())
+		counts[op.kind]++
+	}
+	fmt.Printf("Plan: %d to create, %d to update, %d to delete, %d read-only skipped.\n", counts[opCreate], counts[opUpdate], counts[opDelete], counts[opSkip])
+	if len(plan) == counts[opSkip] || !*flagYes {
+		return
+	}
+
+	for _, op := range plan {
+		switch op.kind {
+		case opCreate:
+			_, err = t.CreateGeofenceContext(ctx, op.trackerID, op.desired)
+		case opUpdate:
+			_, err = t.UpdateGeofenceContext(ctx, op.desired)
+		case opDelete:
+			err = t.DeleteGeofenceContext(ctx, op.current.ID)
+		case opSkip:
+			continue
+		}
+		if err != nil {
+			log.Fatalf("Failed to %s geofence on tracker %s: %v", op.kind, op.trackerID, err)
+		}
+		fmt.Printf("tracker %s: done %s\n", op.trackerID, op.String())
+	}
+}
+
+// planGeofences compares the desired geofences with the ones on the account,
+// and returns the operations needed to reconcile them. Geofences are matched
+// by name. Changes to read-only geofences are planned as skipped. Unless
+// allowDeleteAll is set, an entry whose plan deletes all the geofences of its
+// tracker is an error.
+func planGeofences(ctx context.Context, t *tractive.Tractive, zf *zonesFile, allowDeleteAll bool) ([]*operation, error) {
+	pets, err := t.GetPetsByTrackerContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pets: %w", err)
+	}
+	petTrackers := make(map[string]string, len(pets))
+	for trackerID, pet := range pets {
+		petTrackers[pet.Details.Name] = trackerID
+	}
+
+	var plan []*operation
+	seen := make(map[string]bool)
+	for _, p := range zf.Pets {
+		trackerID := p.Tracker
+		if trackerID == "" {
+			var ok bool
+			if trackerID, ok = petTrackers[p.Pet]; !ok {
+				return nil, fmt.Errorf("no tracker found for pet %q", p.Pet)
+			}
+		}
+		if seen[trackerID] {
+			return nil, fmt.Errorf("tracker %s is listed more than once", trackerID)
+		}
+		seen[trackerID] = true
+
+		desired := make(map[string]*tractive.Geofence, len(p.Geofences))
+		for i := range p.Geofences {
+			g, err := p.Geofences[i].geofence()
+			if err != nil {
+				return nil, fmt.Errorf("tracker %s: %w", trackerID, err)
+			}
+			if _, ok := desired[g.Name]; ok {
+				return nil, fmt.Errorf("tracker %s: geofence %q is listed more than once", trackerID, g.Name)
+			}
+			desired[g.Name] = g
+		}
+
+		list, err := t.GetGeofencesContext(ctx, trackerID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get geofences of tracker %s: %w", trackerID, err)
+		}
+		current := make(map[string]*tractive.Geofence, len(*list))
+		for _, e := range *list {
+			g, err := t.GetGeofenceContext(ctx, e.ID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get geofence %s: %w", e.ID, err)
+			}
+			// geofences are matched by name, so a duplicate couldn't be
+			// told apart from the other one.
+			if other, ok := current[g.Name]; ok {
+				return nil, fmt.Errorf("tracker %s: geofences %s and %s are both named %q, rename one of them", trackerID, other.ID, g.ID, g.Name)
+			}
+			current[g.Name] = g
+		}
+
+		names := make([]string, 0, len(desired)+len(current))
+		for name := range desired {
+			names = append(names, name)
+		}
+		for name := range current {
+			if _, ok := desired[name]; !ok {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		if len(desired) == 0 && !allowDeleteAll {
+			var deletable int
+			for _, c := range current {
+				if !c.ReadOnly {
+					deletable++
+				}
+			}
+			if deletable > 0 {
+				return nil, fmt.Errorf("tracker %s: no geofences listed, which would delete all of its %d geofences; use --allow-delete-all if this is intended", trackerID, deletable)
+			}
+		}
+		for _, name := range names {
+			d, c := desired[name], current[name]
+			switch {
+			case c == nil:
+				plan = append(plan, &operation{kind: opCreate, trackerID: trackerID, desired: d})
+			case c.ReadOnly:
+				if d != nil && !sameGeofence(c, d) {
+					plan = append(plan, &operation{kind: opSkip, trackerID: trackerID, current: c, desired: d})
+				}
+			case d == nil:
+				plan = append(plan, &operation{kind: opDelete, trackerID: trackerID, current: c})
+			case !sameGeofence(c, d):
+				d.Envelope = c.Envelope
+				plan = append(plan, &operation{kind: opUpdate, trackerID: trackerID, current: c, desired: d})
+			}
+		}
+	}
+	return plan, nil
+}
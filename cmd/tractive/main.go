@@ -1,8 +1,11 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"os"
+	"time"
 
 	"github.com/insomniacslk/tractive"
 	"github.com/sirupsen/logrus"
@@ -10,44 +13,121 @@ import (
 )
 
 var (
-	flagToken    = pflag.StringP("token", "t", "", "Token. If empty, username and password must be specified. Requires --user-id")
-	flagUserID   = pflag.StringP("user-id", "i", "", "User ID. If empty, username and password must be set. Requires --token")
-	flagUsername = pflag.StringP("username", "u", "", "Username (e-mail)")
-	flagPassword = pflag.StringP("password", "p", "", "Password")
-	flagDebug    = pflag.BoolP("debug", "D", false, "Enable debug logs (might print sensitive information)")
+	flagToken       = pflag.StringP("token", "t", "", "Token. If empty, username and password must be specified. Requires --user-id")
+	flagUserID      = pflag.StringP("user-id", "i", "", "User ID. If empty, username and password must be set. Requires --token")
+	flagUsername    = pflag.StringP("username", "u", "", "Username (e-mail)")
+	flagPassword    = pflag.StringP("password", "p", "", "Password")
+	flagSessionFile = pflag.StringP("session-file", "s", "", "File where the session is cached between runs. If empty, tractive/session.json in the user config directory")
+	flagDebug       = pflag.BoolP("debug", "D", false, "Enable debug logs of the HTTP traffic. Credentials and tokens are masked, locations are not")
 )
 
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s [flags] [command] [args]\n\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "Commands:\n")
+	fmt.Fprintf(os.Stderr, "  info                               print account, pets and trackers (default)\n")
+	fmt.Fprintf(os.Stderr, "  live-tracking <tracker-id> on|off  enable or disable live tracking\n")
+	fmt.Fprintf(os.Stderr, "  led <tracker-id> on|off            turn the LED on or off\n")
+	fmt.Fprintf(os.Stderr, "  buzzer <tracker-id> on|off         turn the buzzer on or off\n")
+	fmt.Fprintf(os.Stderr, "  export --tracker <tracker-id> ...  export the position history, see export --help\n")
+	fmt.Fprintf(os.Stderr, "  events [--tracker <tracker-id>]    print position, hardware and status events as they happen\n")
+	fmt.Fprintf(os.Stderr, "  trips [--days <n>] ...             print the daily itinerary of every pet, see trips --help\n")
+	fmt.Fprintf(os.Stderr, "  geofences apply -f <file> [--yes]  sync geofences with a YAML file, see geofences apply --help\n")
+	fmt.Fprintf(os.Stderr, "  archive sync|query ...             keep a local archive of positions, see archive <sync|query> --help\n")
+	fmt.Fprintf(os.Stderr, "  logout                             remove the cached session\n\n")
+	fmt.Fprintf(os.Stderr, "Flags:\n")
+	pflag.PrintDefaults()
+}
+
 func main() {
+	pflag.Usage = usage
+	pflag.CommandLine.SetInterspersed(false)
 	pflag.Parse()
 	if *flagDebug {
 		logrus.SetLevel(logrus.DebugLevel)
 	}
-	var (
-		t   *tractive.Tractive
-		err error
-	)
-	if *flagToken == "" {
-		if *flagUsername == "" {
-			log.Fatalf("Empty username and no token specified")
-		}
-		if *flagPassword == "" {
-			log.Fatalf("Empty password and no token specified")
-		}
-		t, err = tractive.Authenticate(*flagUsername, *flagPassword)
-		if err != nil {
-			log.Fatalf("Failed to authenticate: %v", err)
+	store, err := tractive.NewFileTokenStore(*flagSessionFile)
+	if err != nil {
+		log.Fatalf("Failed to get session store: %v", err)
+	}
+
+	cmd, args := "info", pflag.Args()
+	if len(args) > 0 {
+		cmd, args = args[0], args[1:]
+	}
+	switch cmd {
+	case "info":
+		info(newClient(store))
+	case "live-tracking":
+		command(store, tractive.CommandLiveTracking, args)
+	case "led":
+		command(store, tractive.CommandLED, args)
+	case "buzzer":
+		command(store, tractive.CommandBuzzer, args)
+	case "export":
+		exportPositions(store, args)
+	case "events":
+		events(store, args)
+	case "trips":
+		trips(store, args)
+	case "geofences":
+		geofencesCommand(store, args)
+	case "archive":
+		archiveCommand(store, args)
+	case "logout":
+		if err := store.Clear(); err != nil {
+			log.Fatalf("Failed to log out: %v", err)
 		}
-	} else {
+	default:
+		usage()
+		os.Exit(2)
+	}
+}
+
+// newClient returns a client authenticated with the token or the credentials
+// passed on the command line, or with the cached session.
+func newClient(store tractive.TokenStore) *tractive.Tractive {
+	if *flagToken != "" {
 		if *flagUserID == "" {
 			log.Fatalf("Empty user ID")
 		}
-		t = &tractive.Tractive{
-			Token:    *flagToken,
-			ClientID: tractive.ClientID,
-			UserID:   *flagUserID,
+		return tractive.New(tractive.WithToken(*flagUserID, *flagToken))
+	}
+	if *flagUsername == "" && *flagPassword == "" {
+		t := tractive.New(tractive.WithTokenStore(store))
+		if t.Token == "" {
+			log.Fatalf("Not logged in: no token, username or password specified, and no cached session")
 		}
+		return t
+	}
+	if *flagUsername == "" {
+		log.Fatalf("Empty username and no token specified")
 	}
-	fmt.Printf("%+v\n", t)
+	if *flagPassword == "" {
+		log.Fatalf("Empty password and no token specified")
+	}
+	t, err := tractive.Authenticate(*flagUsername, *flagPassword, tractive.WithTokenStore(store))
+	if err != nil {
+		log.Fatalf("Failed to authenticate: %v", err)
+	}
+	return t
+}
+
+func command(store tractive.TokenStore, cmd string, args []string) {
+	if len(args) != 2 || (args[1] != "on" && args[1] != "off") {
+		usage()
+		os.Exit(2)
+	}
+	t := newClient(store)
+	trackerID, on := args[0], args[1] == "on"
+	resp, err := t.SendCommandContext(context.Background(), trackerID, cmd, on)
+	if err != nil {
+		log.Fatalf("Failed to send command %s=%s to tracker %q: %v", cmd, args[1], trackerID, err)
+	}
+	fmt.Printf("Command %s=%s sent to tracker %s: pending=%t\n", cmd, args[1], trackerID, resp.Pending)
+}
+
+func info(t *tractive.Tractive) {
+	fmt.Printf("%+v\n", t.Session())
 
 	// Account Info
 	info, err := t.GetAccountInfo()
@@ -101,14 +181,29 @@ func main() {
 			continue
 		}
 		fmt.Printf("Tracker: %+v\n", tracker)
-		positions, err := t.GetTrackerPositions(tr.ID)
+		hw, err := t.GetTrackerHardware(tr.ID)
+		if err != nil {
+			logrus.Warningf("Failed to get tracker %q 's hardware report: %v", tr.ID, err)
+		} else {
+			fmt.Printf("Tracker hardware: %s\n", hw.String())
+		}
+		loc, err := t.GetTrackerLocation(tr.ID)
+		if err != nil {
+			logrus.Warningf("Failed to get tracker %q 's location: %v", tr.ID, err)
+		} else {
+			fmt.Printf("Tracker location: %s\n", loc.String())
+		}
+		positions, err := t.GetTrackerPositions(tr.ID, time.Now().Add(-time.Hour), time.Now())
 		if err != nil {
 			logrus.Warningf("Failed to get tracker %q 's positions: %v", tr.ID, err)
 			continue
 		}
 		fmt.Printf("Tracker positions:\n")
-		for _, pos := range (*positions)[0] {
-			fmt.Printf("  %s\n", pos.String())
+		for i, seg := range positions.History().Segments() {
+			fmt.Printf(" Segment %d:\n", i)
+			for _, pos := range seg {
+				fmt.Printf("  %s\n", pos.String())
+			}
 		}
 	}
 }
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/insomniacslk/tractive"
+	"github.com/insomniacslk/tractive/analysis"
+	"github.com/spf13/pflag"
+)
+
+func trips(store tractive.TokenStore, args []string) {
+	fs := pflag.NewFlagSet("trips", pflag.ExitOnError)
+	flagTracker := fs.StringP("tracker", "T", "", "Tracker ID. If empty, the trackers of all pets")
+	flagDays := fs.IntP("days", "n", 1, "Number of days to print, including today")
+	flagRadius := fs.Float64("stay-radius", analysis.DefaultSegmentOptions.StayRadius, "Maximum radius of a stay, in meters")
+	flagMinStay := fs.Duration("min-stay", analysis.DefaultSegmentOptions.MinStayDuration, "Minimum duration of a stay")
+	_ = fs.Parse(args)
+	if *flagDays < 1 {
+		log.Fatalf("Invalid number of days %d", *flagDays)
+	}
+	opts := analysis.SegmentOptions{
+		StayRadius:      *flagRadius,
+		MinStayDuration: *flagMinStay,
+	}
+	t := newClient(store)
+	ctx := context.Background()
+
+	pets, err := t.GetPetsByTrackerContext(ctx)
+	if err != nil {
+		log.Fatalf("Failed to get pets: %v", err)
+	}
+	var trackerIDs []string
+	if *flagTracker != "" {
+		trackerIDs = append(trackerIDs, *flagTracker)
+	} else {
+		for trackerID := range pets {
+			trackerIDs = append(trackerIDs, trackerID)
+		}
+		sort.Slice(trackerIDs, func(i, j int) bool {
+			a, b := pets[trackerIDs[i]].Details.Name, pets[trackerIDs[j]].Details.Name
+			if a != b {
+				return a < b
+			}
+			return trackerIDs[i] < trackerIDs[j]
+		})
+	}
+
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	for _, trackerID := range trackerIDs {
+		name := trackerID
+		if pet, ok := pets[trackerID]; ok {
+			name = fmt.Sprintf("%s (tracker %s)", pet.Details.Name, trackerID)
+		}
+		fmt.Printf("%s\n", name)
+		for d := *flagDays - 1; d >= 0; d-- {
+			start := today.AddDate(0, 0, -d)
+			end := start.AddDate(0, 0, 1)
+			if end.After(now) {
+				end = now
+			}
+			history, err := t.GetTrackerPositionHistoryContext(ctx, trackerID, start, end)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to get tracker %q 's positions for %s: %v\n", trackerID, start.Format(time.DateOnly), err)
+				continue
+			}
+			fmt.Printf("  %s\n", start.Format(time.DateOnly))
+			printItinerary(analysis.Segment(history.All(), opts))
+		}
+	}
+}
+
+// printItinerary prints stays and trips in chronological order.
+func printItinerary(stays []*analysis.Stay, trips []*analysis.Trip) {
+	if len(stays) == 0 && len(trips) == 0 {
+		fmt.Printf("    no positions\n")
+		return
+	}
+	for len(stays) > 0 || len(trips) > 0 {
+		if len(trips) == 0 || (len(stays) > 0 && stays[0].Start.Before(trips[0].Start)) {
+			s := stays[0]
+			fmt.Printf("    %s-%s  stay at %.5f,%.5f for %s (%d positions)\n",
+				s.Start.Format("15:04"), s.End.Format("15:04"), s.Lat, s.Lon, s.Duration().Round(time.Minute), s.Positions)
+			stays = stays[1:]
+		} else {
+			tr := trips[0]
+			fmt.Printf("    %s-%s  trip of %.2f km in %s, avg %.1f km/h, max %.1f km/h (%d positions)\n",
+				tr.Start.Format("15:04"), tr.End.Format("15:04"), tr.Distance/1000, tr.Duration().Round(time.Minute), tr.AvgSpeed*3.6, tr.MaxSpeed*3.6, tr.Positions)
+			trips = trips[1:]
+		}
+	}
+}
@@ -2,13 +2,20 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/insomniacslk/tractive"
+	"github.com/insomniacslk/tractive/analysis"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/pflag"
 )
@@ -25,7 +32,15 @@ var (
 	flagOwntracksTID      = pflag.StringP("owntracks-tid", "T", "", "OwnTracks tracker ID (two letters)")
 	flagStartTime         = pflag.IntP("start-time", "s", -1, "Start time as UNIX timestamp (if not specified, default to now-1h)")
 	flagEndTime           = pflag.IntP("end-time", "e", -1, "End time as UNIX timestamp (if not specified, default to now)")
-	flagDebug             = pflag.BoolP("debug", "d", false, "Enable debug logs (might print sensitive information)")
+	flagMaxUncertainty    = pflag.Int("max-uncertainty", 0, "Drop positions with a larger uncertainty, in meters. 0 to keep all")
+	flagMaxSpeed          = pflag.Float64("max-speed", 0, "Drop positions implying a larger speed, in km/h. 0 to keep all")
+	flagDedup             = pflag.Bool("dedup", false, "Drop consecutive positions at the same coordinates")
+	flagSmooth            = pflag.Float64("smooth", 0, "Smooth positions with a Kalman filter with this process noise, in m/s. 0 to disable")
+	flagDaemon            = pflag.Bool("daemon", false, "Keep running, pushing new positions every --interval")
+	flagInterval          = pflag.Duration("interval", 5*time.Minute, "Polling interval in daemon mode")
+	flagStateFile         = pflag.String("state-file", "", "File where the last pushed position of every tracker is recorded, so that only new positions are sent. If empty, no state is kept, except in daemon mode where tractive/tractive2owntracks.json in the user config directory is used")
+	flagSessionFile       = pflag.String("session-file", "", "File where the Tractive session is cached between runs. If empty, tractive/session.json in the user config directory")
+	flagDebug             = pflag.BoolP("debug", "d", false, "Enable debug logs of the HTTP traffic. Credentials and tokens are masked, locations are not")
 )
 
 type OwnTracksDatapoint struct {
@@ -43,30 +58,42 @@ func main() {
 	if *flagDebug {
 		logrus.SetLevel(logrus.DebugLevel)
 	}
-	var (
-		t   *tractive.Tractive
-		err error
-	)
-	if *flagTractiveToken == "" {
+	store, err := tractive.NewFileTokenStore(*flagSessionFile)
+	if err != nil {
+		logrus.Fatalf("Failed to get session store: %v", err)
+	}
+	if pflag.NArg() > 0 {
+		if pflag.Arg(0) != "logout" {
+			logrus.Fatalf("Unknown command %q", pflag.Arg(0))
+		}
+		if err := store.Clear(); err != nil {
+			logrus.Fatalf("Failed to log out: %v", err)
+		}
+		return
+	}
+	var t *tractive.Tractive
+	switch {
+	case *flagTractiveToken != "":
+		if *flagTractiveUserID == "" {
+			logrus.Fatalf("Empty user ID")
+		}
+		t = tractive.New(tractive.WithToken(*flagTractiveUserID, *flagTractiveToken))
+	case *flagTractiveUsername == "" && *flagTractivePassword == "":
+		t = tractive.New(tractive.WithTokenStore(store))
+		if t.Token == "" {
+			logrus.Fatalf("Not logged in: no token, username or password specified, and no cached session")
+		}
+	default:
 		if *flagTractiveUsername == "" {
 			logrus.Fatalf("Empty username and no token specified")
 		}
 		if *flagTractivePassword == "" {
 			logrus.Fatalf("Empty password and no token specified")
 		}
-		t, err = tractive.Authenticate(*flagTractiveUsername, *flagTractivePassword)
+		t, err = tractive.Authenticate(*flagTractiveUsername, *flagTractivePassword, tractive.WithTokenStore(store))
 		if err != nil {
 			logrus.Fatalf("Failed to authenticate: %v", err)
 		}
-	} else {
-		if *flagTractiveUserID == "" {
-			logrus.Fatalf("Empty user ID")
-		}
-		t = &tractive.Tractive{
-			Token:    *flagTractiveToken,
-			ClientID: tractive.ClientID,
-			UserID:   *flagTractiveUserID,
-		}
 	}
 	if *flagOwntracksEndpoint == "" {
 		logrus.Fatalf("owntracks-endpoint is not set")
@@ -75,98 +102,215 @@ func main() {
 		logrus.Fatalf("owntracks-tid is not set")
 	}
 
-	// Pets
-	trackersToPets := make(map[string]*tractive.PetResponse, 0)
-	pets, err := t.GetPets()
+	if *flagDaemon && *flagEndTime != -1 {
+		logrus.Fatalf("end-time can't be used with --daemon")
+	}
+	if *flagInterval <= 0 {
+		logrus.Fatalf("Invalid interval %s", *flagInterval)
+	}
+	owntracksEndpoint, err := url.Parse(*flagOwntracksEndpoint)
 	if err != nil {
-		logrus.Fatalf("Failed to get pets: %v", err)
+		logrus.Fatalf("Failed to parse OwnTracks endpoint URL: %v", err)
 	}
-	logrus.Infof("Found %d pets", len(*pets))
-	for _, p := range *pets {
-		pet, err := t.GetPet(p.ID)
+	statePath := *flagStateFile
+	if statePath == "" && *flagDaemon {
+		statePath, err = defaultStatePath()
 		if err != nil {
-			logrus.Warningf("Failed to get pet %q: %v", p.ID, err)
+			logrus.Fatalf("Failed to get state file: %v", err)
+		}
+	}
+	s := syncer{
+		t: t,
+		pipeline: analysis.NewPipeline(analysis.FilterOptions{
+			MaxUncertainty: *flagMaxUncertainty,
+			Dedup:          *flagDedup,
+			MaxSpeed:       *flagMaxSpeed / 3.6,
+			Smooth:         *flagSmooth,
+		}),
+		endpoint: owntracksEndpoint,
+		client:   &http.Client{Timeout: 30 * time.Second},
+		// an explicit start time overrides the state, for the first poll.
+		resume: *flagStartTime == -1,
+	}
+	if statePath != "" {
+		s.state, err = loadState(statePath)
+		if err != nil {
+			logrus.Fatalf("Failed to load state: %v", err)
 		}
-		logrus.Infof("  Pet: %+v\n", pet)
-		trackersToPets[pet.DeviceID] = pet
 	}
 
-	// Trackers
-	trackers, err := t.GetAllTrackers()
-	if err != nil {
-		logrus.Fatalf("Failed to get trackers: %v", err)
+	if !*flagDaemon {
+		end := time.Now()
+		if *flagEndTime != -1 {
+			end = time.Unix(int64(*flagEndTime), 0)
+		}
+		if err := s.run(context.Background(), startTime(), end); err != nil {
+			logrus.Fatalf("Sync failed: %v", err)
+		}
+		return
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	ticker := time.NewTicker(*flagInterval)
+	defer ticker.Stop()
+	start := startTime()
+	for {
+		end := time.Now()
+		if err := s.run(ctx, start, end); err != nil {
+			if ctx.Err() == nil {
+				logrus.Warningf("Sync failed, retrying in %s: %v", *flagInterval, err)
+			}
+		} else {
+			// trackers without a state continue from the end of the
+			// last successful poll, so that no positions are skipped
+			// whatever the interval.
+			start = end
+		}
+		// --start-time only applies to the first poll, the following ones
+		// resume from the last pushed positions.
+		s.resume = true
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
 	}
-	logrus.Infof("Found %d trackers", len(*trackers))
-	start := time.Now().Add(-time.Hour)
-	end := time.Now()
+}
+
+// startTime returns the start of the time range to push, for trackers
+// without a recorded state.
+func startTime() time.Time {
 	if *flagStartTime != -1 {
-		start = time.Unix(int64(*flagStartTime), 0)
+		return time.Unix(int64(*flagStartTime), 0)
 	}
-	if *flagEndTime != -1 {
-		end = time.Unix(int64(*flagEndTime), 0)
+	return time.Now().Add(-time.Hour)
+}
+
+// syncer pushes the positions of all trackers to OwnTracks.
+type syncer struct {
+	t        *tractive.Tractive
+	pipeline analysis.Pipeline
+	endpoint *url.URL
+	client   *http.Client
+	// state is nil if no state file is used.
+	state *state
+	// resume tells whether trackers with a recorded state resume from their
+	// last pushed position rather than from the start of the time range.
+	resume bool
+}
+
+// run pushes the positions between start and end of every tracker. A
+// failure doesn't stop the other trackers; the errors of all trackers are
+// returned together.
+func (s *syncer) run(ctx context.Context, start, end time.Time) error {
+	trackersToPets, err := s.t.GetPetsByTrackerContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get pets: %w", err)
 	}
-	logrus.Infof("Querying time range: %s   -->   %s\n", start, end)
-	owntracksEndpoint, err := url.Parse(*flagOwntracksEndpoint)
+	logrus.Debugf("Found %d pets", len(trackersToPets))
+	trackers, err := s.t.GetAllTrackersContext(ctx)
 	if err != nil {
-		logrus.Fatalf("Failed to parse OwnTracks endpoint URL: %v", err)
+		return fmt.Errorf("failed to get trackers: %w", err)
 	}
+	logrus.Debugf("Found %d trackers", len(*trackers))
+	var errs []error
 	for _, tr := range *trackers {
-		tracker, err := t.GetTracker(tr.ID)
-		if err != nil {
-			logrus.Warningf("Failed to get tracker %q: %v", tr.ID, err)
-			continue
-		}
 		pet, ok := trackersToPets[tr.ID]
 		if !ok {
-			logrus.Fatalf("No pet found for tracker ID %q", tr.ID)
-		}
-		q := owntracksEndpoint.Query()
-		q.Add("u", pet.Details.Name)
-		q.Add("d", "tractive")
-		owntracksEndpoint.RawQuery = q.Encode()
-		logrus.Infof("Tracker: %+v\n", tracker)
-		positions, err := t.GetTrackerPositions(tr.ID, start, end)
-		if err != nil {
-			logrus.Warningf("Failed to get tracker %q 's positions: %v", tr.ID, err)
+			logrus.Warningf("No pet found for tracker ID %q", tr.ID)
 			continue
 		}
-		logrus.Debugf("Tracker positions:\n")
-		client := http.Client{}
-		for _, pos := range (*positions)[0] {
-			dp := OwnTracksDatapoint{
-				Type:      "location",
-				Latitude:  pos.LatLong[0],
-				Longitude: pos.LatLong[1],
-				Timestamp: pos.Time,
-				Accuracy:  pos.PosUncertainty,
-				Altitude:  pos.Alt,
-				TID:       *flagOwntracksTID,
-			}
-			logrus.Debugf("  pos=%s\n", pos.String())
-			logrus.Debugf("  dp=%+v\n", dp)
-			postData, err := json.Marshal(dp)
-			if err != nil {
-				logrus.Fatalf("Failed to marshal owntracks JSON payload: %v", err)
-			}
-			logrus.Debugf("Sending request to %s", owntracksEndpoint)
-			logrus.Debugf("POST payload: %s", postData)
-			req, err := http.NewRequest(http.MethodPost, owntracksEndpoint.String(), bytes.NewBuffer(postData))
-			req.Header.Set("Content-Type", "application/json")
-			// TODO set owntracks username and password
-			resp, err := client.Do(req)
-			if err != nil {
-				logrus.Fatalf("Failed to make POST request to OwnTracks: %v", err)
-			}
-			defer resp.Body.Close()
-			if resp.StatusCode != 200 {
-				logrus.Fatalf("POST request to OwnTracks failed with %s", resp.Status)
-			}
-			body, err := io.ReadAll(resp.Body)
-			if err != nil {
-				logrus.Fatalf("Failed to read response body: %v", err)
-			}
-			logrus.Debugf("Response: %s\n", string(body))
+		if err := s.syncTracker(ctx, tr.ID, pet.Details.Name, start, end); err != nil {
+			errs = append(errs, fmt.Errorf("tracker %s: %w", tr.ID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s *syncer) syncTracker(ctx context.Context, trackerID, petName string, start, end time.Time) error {
+	var last int64
+	if s.state != nil {
+		last = s.state.LastPushed[trackerID]
+		if last != 0 && s.resume {
+			start = time.Unix(last+1, 0)
+		}
+	}
+	if !start.Before(end) {
+		return nil
+	}
+	logrus.Infof("Querying tracker %s from %s to %s", trackerID, start, end)
+	history, err := s.t.GetTrackerPositionHistoryContext(ctx, trackerID, start, end)
+	if err != nil {
+		return fmt.Errorf("failed to get positions: %w", err)
+	}
+	history = s.pipeline.ApplyHistory(history)
+
+	endpoint := *s.endpoint
+	q := endpoint.Query()
+	q.Set("u", petName)
+	q.Set("d", "tractive")
+	endpoint.RawQuery = q.Encode()
+	pushed := 0
+	for _, pos := range history.All() {
+		if pos.Time <= last {
+			continue
 		}
-		logrus.Infof("Pushed %d positions for tracker %s", len((*positions)[0]), tr.ID)
+		if err = s.push(ctx, endpoint, pos); err != nil {
+			break
+		}
+		pushed++
+		last = pos.Time
+	}
+	logrus.Infof("Pushed %d positions for tracker %s", pushed, trackerID)
+	if s.state != nil && pushed > 0 {
+		// record what was pushed even after a failure, so that it isn't
+		// sent again.
+		s.state.LastPushed[trackerID] = last
+		if saveErr := s.state.save(); saveErr != nil {
+			return errors.Join(err, saveErr)
+		}
+	}
+	return err
+}
+
+// push sends a position to OwnTracks.
+func (s *syncer) push(ctx context.Context, endpoint url.URL, pos tractive.TrackerPosition) error {
+	dp := OwnTracksDatapoint{
+		Type:      "location",
+		Latitude:  pos.LatLong[0],
+		Longitude: pos.LatLong[1],
+		Timestamp: pos.Time,
+		Accuracy:  pos.PosUncertainty,
+		Altitude:  pos.Alt,
+		TID:       *flagOwntracksTID,
+	}
+	logrus.Debugf("  pos=%s\n", pos.String())
+	logrus.Debugf("  dp=%+v\n", dp)
+	postData, err := json.Marshal(dp)
+	if err != nil {
+		return fmt.Errorf("failed to marshal owntracks JSON payload: %w", err)
+	}
+	logrus.Debugf("Sending request to %s", endpoint.String())
+	logrus.Debugf("POST payload: %s", postData)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.String(), bytes.NewReader(postData))
+	if err != nil {
+		return fmt.Errorf("failed to create http request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	// TODO set owntracks username and password
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to make POST request to OwnTracks: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("POST request to OwnTracks failed with %s", resp.Status)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %w", err)
 	}
+	logrus.Debugf("Response: %s\n", string(body))
+	return nil
 }
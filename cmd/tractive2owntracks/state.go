@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/insomniacslk/tractive/internal/fileutil"
+)
+
+// state records the timestamp of the last position pushed for every tracker,
+// so that the following runs only send new positions.
+type state struct {
+	path string
+	// LastPushed maps tracker IDs to UNIX timestamps.
+	LastPushed map[string]int64 `json:"last_pushed"`
+}
+
+// defaultStatePath returns tractive/tractive2owntracks.json in the user
+// config directory.
+func defaultStatePath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get user config dir: %w", err)
+	}
+	return filepath.Join(dir, "tractive", "tractive2owntracks.json"), nil
+}
+
+// loadState reads the state file, returning an empty state if it doesn't
+// exist.
+func loadState(path string) (*state, error) {
+	s := state{path: path, LastPushed: map[string]int64{}}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return &s, nil
+		}
+		return nil, fmt.Errorf("failed to read state file: %w", err)
+	}
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal state file: %w", err)
+	}
+	if s.LastPushed == nil {
+		s.LastPushed = map[string]int64{}
+	}
+	return &s, nil
+}
+
+func (s *state) save() error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("failed to marshal state: %w", err)
+	}
+	if err := fileutil.WriteAtomic(s.path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	return nil
+}
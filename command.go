@@ -0,0 +1,61 @@
+package tractive
+
+import (
+	"context"
+	"net/http"
+)
+
+// Tracker commands, as used in the command endpoint paths.
+const (
+	CommandLiveTracking = "live_tracking"
+	CommandLED          = "led_control"
+	CommandBuzzer       = "buzzer_control"
+)
+
+// CommandResponse is the acknowledgement of a tracker command. The command
+// is executed asynchronously by the tracker: Pending is true until the
+// tracker has confirmed it.
+type CommandResponse struct {
+	Envelope
+	Pending      bool `json:"pending"`
+	Reconnecting bool `json:"reconnecting"`
+}
+
+func (t *Tractive) SetLiveTracking(trackerID string, on bool) (*CommandResponse, error) {
+	return t.SetLiveTrackingContext(context.Background(), trackerID, on)
+}
+
+func (t *Tractive) SetLiveTrackingContext(ctx context.Context, trackerID string, on bool) (*CommandResponse, error) {
+	return t.SendCommandContext(ctx, trackerID, CommandLiveTracking, on)
+}
+
+func (t *Tractive) SetLED(trackerID string, on bool) (*CommandResponse, error) {
+	return t.SetLEDContext(context.Background(), trackerID, on)
+}
+
+func (t *Tractive) SetLEDContext(ctx context.Context, trackerID string, on bool) (*CommandResponse, error) {
+	return t.SendCommandContext(ctx, trackerID, CommandLED, on)
+}
+
+func (t *Tractive) SetBuzzer(trackerID string, on bool) (*CommandResponse, error) {
+	return t.SetBuzzerContext(context.Background(), trackerID, on)
+}
+
+func (t *Tractive) SetBuzzerContext(ctx context.Context, trackerID string, on bool) (*CommandResponse, error) {
+	return t.SendCommandContext(ctx, trackerID, CommandBuzzer, on)
+}
+
+func (t *Tractive) SendCommand(trackerID, command string, on bool) (*CommandResponse, error) {
+	return t.SendCommandContext(context.Background(), trackerID, command, on)
+}
+
+// SendCommandContext turns the given command on or off on a tracker. See the
+// Command* constants.
+func (t *Tractive) SendCommandContext(ctx context.Context, trackerID, command string, on bool) (*CommandResponse, error) {
+	state := "off"
+	if on {
+		state = "on"
+	}
+	u := t.tractiveURL("/4/tracker/" + trackerID + "/command/" + command + "/" + state)
+	return callAPI[CommandResponse](ctx, t, http.MethodGet, u, nil)
+}
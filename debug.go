@@ -0,0 +1,149 @@
+package tractive
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+const redacted = "<redacted>"
+
+// sensitiveHeaders are the HTTP headers whose value is masked in debug logs.
+var sensitiveHeaders = map[string]bool{
+	"Authorization": true,
+	"Cookie":        true,
+	"Set-Cookie":    true,
+}
+
+// sensitiveKeys are the JSON fields and query parameters whose value is
+// masked in debug logs.
+var sensitiveKeys = map[string]bool{
+	"access_token":   true,
+	"refresh_token":  true,
+	"token":          true,
+	"password":       true,
+	"platform_token": true,
+}
+
+// coordinateKeys are the JSON fields and query parameters whose value is
+// masked in debug logs when coordinate redaction is enabled.
+var coordinateKeys = map[string]bool{
+	"latlong":     true,
+	"lat":         true,
+	"lon":         true,
+	"lng":         true,
+	"latitude":    true,
+	"longitude":   true,
+	"coordinates": true,
+}
+
+// WithRedactCoordinates masks coordinates, in addition to credentials and
+// tokens, in the debug logs of HTTP traffic.
+func WithRedactCoordinates(redact bool) Option {
+	return func(t *Tractive) {
+		t.redactCoordinates = redact
+	}
+}
+
+// traceRequest logs a redacted dump of an outgoing request.
+func (t *Tractive) traceRequest(req *http.Request, body []byte) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s\n", req.Method, t.redactURL(*req.URL))
+	writeHeaders(&b, req.Header)
+	if len(body) > 0 {
+		fmt.Fprintf(&b, "\n%s\n", t.redactBody(body))
+	}
+	t.getLogger().Debugf("HTTP REQUEST:\n%s", b.String())
+}
+
+// traceResponse logs a redacted dump of a response.
+func (t *Tractive) traceResponse(resp *http.Response, body []byte) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s\n", resp.Proto, resp.Status)
+	writeHeaders(&b, resp.Header)
+	if len(body) > 0 {
+		fmt.Fprintf(&b, "\n%s\n", t.redactBody(body))
+	}
+	t.getLogger().Debugf("HTTP RESPONSE:\n%s", b.String())
+}
+
+func writeHeaders(b *strings.Builder, h http.Header) {
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		for _, v := range h[k] {
+			if sensitiveHeaders[http.CanonicalHeaderKey(k)] {
+				v = redactHeader(v)
+			}
+			fmt.Fprintf(b, "%s: %s\n", k, v)
+		}
+	}
+}
+
+// redactHeader masks a header value, keeping the authentication scheme if
+// any, e.g. "Bearer <redacted>".
+func redactHeader(v string) string {
+	if scheme, _, ok := strings.Cut(v, " "); ok {
+		return scheme + " " + redacted
+	}
+	return redacted
+}
+
+func (t *Tractive) isRedactedKey(k string) bool {
+	k = strings.ToLower(k)
+	return sensitiveKeys[k] || (t.redactCoordinates && coordinateKeys[k])
+}
+
+func (t *Tractive) redactURL(u url.URL) string {
+	q := u.Query()
+	for k := range q {
+		if t.isRedactedKey(k) {
+			q.Set(k, redacted)
+		}
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// redactBody masks the sensitive fields of a JSON body. Bodies that are not
+// JSON are returned as they are.
+func (t *Tractive) redactBody(body []byte) string {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return string(body)
+	}
+	var out bytes.Buffer
+	enc := json.NewEncoder(&out)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(t.redactValue(v)); err != nil {
+		return string(body)
+	}
+	return strings.TrimSuffix(out.String(), "\n")
+}
+
+func (t *Tractive) redactValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, val := range v {
+			if t.isRedactedKey(k) {
+				v[k] = redacted
+			} else {
+				v[k] = t.redactValue(val)
+			}
+		}
+	case []interface{}:
+		for i, val := range v {
+			v[i] = t.redactValue(val)
+		}
+	}
+	return v
+}
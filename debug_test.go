@@ -0,0 +1,97 @@
+package tractive
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// captureLogger records the messages logged at any level.
+type captureLogger struct {
+	mu  sync.Mutex
+	buf strings.Builder
+}
+
+func (l *captureLogger) logf(format string, args ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	fmt.Fprintf(&l.buf, format+"\n", args...)
+}
+
+func (l *captureLogger) Debugf(format string, args ...interface{})   { l.logf(format, args...) }
+func (l *captureLogger) Infof(format string, args ...interface{})    { l.logf(format, args...) }
+func (l *captureLogger) Warningf(format string, args ...interface{}) { l.logf(format, args...) }
+
+func (l *captureLogger) String() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.buf.String()
+}
+
+const (
+	testPassword = "hunter2-password"
+	testToken    = "secret-access-token"
+)
+
+// traceSession logs in and gets the positions of a tracker from a fake API,
+// and returns the debug log.
+func traceSession(t *testing.T, opts ...Option) string {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/4/auth/token":
+			fmt.Fprintf(w, `{"user_id":"U1","client_id":"C1","access_token":%q,"expires_at":%d}`, testToken, time.Now().Add(time.Hour).Unix())
+		case "/4/tracker/T1/positions":
+			fmt.Fprint(w, `[[{"time":1700000000,"latlong":[48.123456,16.654321],"alt":180,"pos_uncertainty":5}]]`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	base, _ := url.Parse(srv.URL)
+	logger := &captureLogger{}
+	tr, err := Authenticate("user@example.com", testPassword, append([]Option{WithBaseURL(base), WithLogger(logger)}, opts...)...)
+	if err != nil {
+		t.Fatalf("Authenticate failed: %v", err)
+	}
+	if _, err := tr.GetTrackerPositions("T1", time.Unix(1699990000, 0), time.Unix(1700010000, 0)); err != nil {
+		t.Fatalf("GetTrackerPositions failed: %v", err)
+	}
+	return logger.String()
+}
+
+func TestTraceRedactsSecrets(t *testing.T) {
+	log := traceSession(t)
+	for _, secret := range []string{testPassword, testToken} {
+		if strings.Contains(log, secret) {
+			t.Errorf("debug log contains %q:\n%s", secret, log)
+		}
+	}
+	for _, want := range []string{
+		`"platform_token":"<redacted>"`,
+		`"access_token":"<redacted>"`,
+		"Authorization: Bearer <redacted>",
+		// coordinates are only masked on request.
+		"48.123456",
+	} {
+		if !strings.Contains(log, want) {
+			t.Errorf("debug log doesn't contain %q:\n%s", want, log)
+		}
+	}
+}
+
+func TestTraceRedactsCoordinates(t *testing.T) {
+	log := traceSession(t, WithRedactCoordinates(true))
+	for _, secret := range []string{testPassword, testToken, "48.123456", "16.654321"} {
+		if strings.Contains(log, secret) {
+			t.Errorf("debug log contains %q:\n%s", secret, log)
+		}
+	}
+	if !strings.Contains(log, `"latlong":"<redacted>"`) {
+		t.Errorf("debug log doesn't contain redacted coordinates:\n%s", log)
+	}
+}
@@ -0,0 +1,104 @@
+package tractive
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Sentinel errors matching an *APIError with errors.Is.
+var (
+	// ErrUnauthorized is returned when the API rejects the credentials or
+	// the access token.
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrForbidden is returned when the user can't access a resource.
+	ErrForbidden = errors.New("forbidden")
+	// ErrNotFound is returned when a resource, e.g. a tracker, doesn't exist.
+	ErrNotFound = errors.New("not found")
+	// ErrRateLimited is returned when the API rate limit was hit, and the
+	// retries were exhausted.
+	ErrRateLimited = errors.New("rate limited")
+	// ErrServer is returned when the API fails with a 5xx status.
+	ErrServer = errors.New("server error")
+)
+
+// requestIDHeaders are the response headers that may carry the ID of a
+// request, in order of preference.
+var requestIDHeaders = []string{"X-Request-Id", "X-Tractive-Request-Id", "X-Amzn-Trace-Id"}
+
+// APIError is returned when the API answers with a non-2xx status.
+type APIError struct {
+	// StatusCode is the HTTP status code, and Status the full status line.
+	StatusCode int
+	Status     string
+	// Code, Category and Message are taken from the JSON error body
+	// returned by Tractive, if any.
+	Code     int
+	Category string
+	Message  string
+	// Method and Path identify the failed request.
+	Method string
+	Path   string
+	// RequestID is the ID of the request as reported by the server, if any.
+	RequestID string
+	// RetryAfter is the value of the Retry-After header, if any.
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("%s %s: http status is %s, expected 2xx", e.Method, e.Path, e.Status)
+	if e.Code != 0 || e.Message != "" {
+		msg += fmt.Sprintf(" (code=%d message=%q)", e.Code, e.Message)
+	}
+	if e.RequestID != "" {
+		msg += fmt.Sprintf(" request_id=%s", e.RequestID)
+	}
+	return msg
+}
+
+// Is makes APIError match the sentinel error corresponding to its status.
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrForbidden:
+		return e.StatusCode == http.StatusForbidden
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrServer:
+		return e.StatusCode >= 500
+	}
+	return false
+}
+
+// newAPIError builds an APIError from a failed response and its body.
+func newAPIError(resp *http.Response, body []byte) *APIError {
+	e := APIError{
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		Method:     resp.Request.Method,
+		Path:       resp.Request.URL.Path,
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+	}
+	for _, h := range requestIDHeaders {
+		if id := resp.Header.Get(h); id != "" {
+			e.RequestID = id
+			break
+		}
+	}
+	var eb struct {
+		Code     int    `json:"code"`
+		Category string `json:"category"`
+		Message  string `json:"message"`
+	}
+	if err := json.Unmarshal(body, &eb); err == nil {
+		e.Code = eb.Code
+		e.Category = eb.Category
+		e.Message = eb.Message
+	}
+	return &e
+}
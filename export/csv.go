@@ -0,0 +1,78 @@
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/insomniacslk/tractive"
+)
+
+// CSVColumns are the columns written by CSVWriter.
+var CSVColumns = []string{
+	"tracker_id",
+	"pet_name",
+	"time",
+	"timestamp",
+	"lat",
+	"lon",
+	"alt",
+	"speed",
+	"course",
+	"pos_uncertainty",
+	"sensor_used",
+}
+
+// CSVWriter writes positions as a table, one row per position. Rows are
+// written as they are passed, so that arbitrarily long histories can be
+// exported without holding them in memory.
+type CSVWriter struct {
+	w             *csv.Writer
+	headerWritten bool
+}
+
+// NewCSVWriter returns a CSVWriter using the given field separator, e.g. ','
+// for CSV or '\t' for TSV.
+func NewCSVWriter(w io.Writer, comma rune) *CSVWriter {
+	cw := csv.NewWriter(w)
+	cw.Comma = comma
+	return &CSVWriter{w: cw}
+}
+
+// Write writes a row for each of the given positions of a tracker. The
+// header is written before the first row.
+func (c *CSVWriter) Write(trackerID, petName string, positions ...tractive.TrackerPosition) error {
+	if !c.headerWritten {
+		if err := c.w.Write(CSVColumns); err != nil {
+			return fmt.Errorf("failed to write CSV header: %w", err)
+		}
+		c.headerWritten = true
+	}
+	for _, p := range positions {
+		row := []string{
+			trackerID,
+			petName,
+			time.Unix(p.Time, 0).UTC().Format(time.RFC3339),
+			strconv.FormatInt(p.Time, 10),
+			strconv.FormatFloat(p.LatLong[0], 'f', -1, 64),
+			strconv.FormatFloat(p.LatLong[1], 'f', -1, 64),
+			strconv.Itoa(p.Alt),
+			strconv.FormatFloat(p.Speed, 'f', -1, 64),
+			strconv.Itoa(p.Course),
+			strconv.Itoa(p.PosUncertainty),
+			p.SensorUsed,
+		}
+		if err := c.w.Write(row); err != nil {
+			return fmt.Errorf("failed to write CSV row: %w", err)
+		}
+	}
+	return nil
+}
+
+// Flush writes any buffered data to the underlying writer.
+func (c *CSVWriter) Flush() error {
+	c.w.Flush()
+	return c.w.Error()
+}
@@ -0,0 +1,98 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/insomniacslk/tractive"
+)
+
+type geoJSONFeatureCollection struct {
+	Type     string           `json:"type"`
+	Features []geoJSONFeature `json:"features"`
+}
+
+type geoJSONFeature struct {
+	Type       string                 `json:"type"`
+	Geometry   geoJSONGeometry        `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+type geoJSONGeometry struct {
+	Type        string      `json:"type"`
+	Coordinates interface{} `json:"coordinates"`
+}
+
+// geoJSONPosition returns the GeoJSON coordinates of a position, i.e.
+// longitude, latitude and altitude.
+func geoJSONPosition(p tractive.TrackerPosition) [3]float64 {
+	return [3]float64{p.LatLong[1], p.LatLong[0], float64(p.Alt)}
+}
+
+// WriteGeoJSON writes a position history as a GeoJSON FeatureCollection.
+// Every segment of the history becomes a LineString feature, or a Point
+// feature if it has a single position. If points is true, every position also
+// becomes a Point feature, whose properties carry its time, speed, course,
+// uncertainty and sensor.
+func WriteGeoJSON(w io.Writer, name string, h *tractive.PositionHistory, points bool) error {
+	fc := geoJSONFeatureCollection{
+		Type:     "FeatureCollection",
+		Features: []geoJSONFeature{},
+	}
+	for i, s := range h.Segments() {
+		// a LineString needs at least two positions.
+		geometry := geoJSONGeometry{
+			Type:        "Point",
+			Coordinates: geoJSONPosition(s[0]),
+		}
+		if len(s) > 1 {
+			coords := make([][3]float64, 0, len(s))
+			for _, p := range s {
+				coords = append(coords, geoJSONPosition(p))
+			}
+			geometry = geoJSONGeometry{
+				Type:        "LineString",
+				Coordinates: coords,
+			}
+		}
+		fc.Features = append(fc.Features, geoJSONFeature{
+			Type:     "Feature",
+			Geometry: geometry,
+			Properties: map[string]interface{}{
+				"name":    name,
+				"segment": i,
+				"start":   time.Unix(s[0].Time, 0).UTC().Format(time.RFC3339),
+				"end":     time.Unix(s[len(s)-1].Time, 0).UTC().Format(time.RFC3339),
+			},
+		})
+		if !points {
+			continue
+		}
+		for _, p := range s {
+			fc.Features = append(fc.Features, geoJSONFeature{
+				Type: "Feature",
+				Geometry: geoJSONGeometry{
+					Type:        "Point",
+					Coordinates: geoJSONPosition(p),
+				},
+				Properties: map[string]interface{}{
+					"name":            name,
+					"segment":         i,
+					"time":            time.Unix(p.Time, 0).UTC().Format(time.RFC3339),
+					"speed":           p.Speed,
+					"course":          p.Course,
+					"pos_uncertainty": p.PosUncertainty,
+					"sensor_used":     p.SensorUsed,
+				},
+			})
+		}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(fc); err != nil {
+		return fmt.Errorf("failed to encode GeoJSON: %w", err)
+	}
+	return nil
+}
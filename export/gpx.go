@@ -0,0 +1,98 @@
+// Package export writes tracker position histories in formats understood
+// by mapping and analysis tools.
+package export
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/insomniacslk/tractive"
+)
+
+// GPXExtensionsNamespace is the XML namespace of the GPX extensions carrying
+// the Tractive-specific fields of a position.
+const GPXExtensionsNamespace = "https://github.com/insomniacslk/tractive/gpx/1"
+
+type gpx struct {
+	XMLName       xml.Name `xml:"gpx"`
+	Version       string   `xml:"version,attr"`
+	Creator       string   `xml:"creator,attr"`
+	Xmlns         string   `xml:"xmlns,attr"`
+	XmlnsTractive string   `xml:"xmlns:tractive,attr"`
+	Metadata      struct {
+		Name string `xml:"name,omitempty"`
+		Time string `xml:"time"`
+	} `xml:"metadata"`
+	Tracks []gpxTrack `xml:"trk"`
+}
+
+type gpxTrack struct {
+	Name     string       `xml:"name,omitempty"`
+	Segments []gpxSegment `xml:"trkseg"`
+}
+
+type gpxSegment struct {
+	Points []gpxPoint `xml:"trkpt"`
+}
+
+type gpxPoint struct {
+	Lat        float64 `xml:"lat,attr"`
+	Lon        float64 `xml:"lon,attr"`
+	Ele        int     `xml:"ele"`
+	Time       string  `xml:"time"`
+	Extensions struct {
+		Accuracy int     `xml:"tractive:accuracy"`
+		Sensor   string  `xml:"tractive:sensor,omitempty"`
+		Speed    float64 `xml:"tractive:speed"`
+		Course   int     `xml:"tractive:course"`
+	} `xml:"extensions"`
+}
+
+// WriteGPX writes a position history as a GPX 1.1 document with a single
+// track named `name`. Every segment of the history becomes a track segment,
+// and the accuracy, sensor, speed and course of every position are written
+// as extensions in the GPXExtensionsNamespace namespace.
+func WriteGPX(w io.Writer, name string, h *tractive.PositionHistory) error {
+	doc := gpx{
+		Version:       "1.1",
+		Creator:       "github.com/insomniacslk/tractive",
+		Xmlns:         "http://www.topografix.com/GPX/1/1",
+		XmlnsTractive: GPXExtensionsNamespace,
+	}
+	doc.Metadata.Name = name
+	doc.Metadata.Time = time.Now().UTC().Format(time.RFC3339)
+	trk := gpxTrack{Name: name}
+	for _, s := range h.Segments() {
+		var seg gpxSegment
+		for _, p := range s {
+			pt := gpxPoint{
+				Lat:  p.LatLong[0],
+				Lon:  p.LatLong[1],
+				Ele:  p.Alt,
+				Time: time.Unix(p.Time, 0).UTC().Format(time.RFC3339),
+			}
+			pt.Extensions.Accuracy = p.PosUncertainty
+			pt.Extensions.Sensor = p.SensorUsed
+			pt.Extensions.Speed = p.Speed
+			pt.Extensions.Course = p.Course
+			seg.Points = append(seg.Points, pt)
+		}
+		trk.Segments = append(trk.Segments, seg)
+	}
+	doc.Tracks = append(doc.Tracks, trk)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return fmt.Errorf("failed to write GPX header: %w", err)
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return fmt.Errorf("failed to encode GPX: %w", err)
+	}
+	if _, err := io.WriteString(w, "\n"); err != nil {
+		return fmt.Errorf("failed to write GPX: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package export
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/insomniacslk/tractive"
+)
+
+type kml struct {
+	XMLName  xml.Name `xml:"kml"`
+	Xmlns    string   `xml:"xmlns,attr"`
+	Document struct {
+		Name       string         `xml:"name,omitempty"`
+		Placemarks []kmlPlacemark `xml:"Placemark"`
+	} `xml:"Document"`
+}
+
+type kmlPlacemark struct {
+	Name         string           `xml:"name,omitempty"`
+	TimeStamp    *kmlTimeStamp    `xml:"TimeStamp,omitempty"`
+	TimeSpan     *kmlTimeSpan     `xml:"TimeSpan,omitempty"`
+	ExtendedData *kmlExtendedData `xml:"ExtendedData,omitempty"`
+	LineString   *kmlGeometry     `xml:"LineString,omitempty"`
+	Point        *kmlGeometry     `xml:"Point,omitempty"`
+}
+
+type kmlTimeStamp struct {
+	When string `xml:"when"`
+}
+
+type kmlTimeSpan struct {
+	Begin string `xml:"begin"`
+	End   string `xml:"end"`
+}
+
+type kmlExtendedData struct {
+	Data []kmlData `xml:"Data"`
+}
+
+type kmlData struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value"`
+}
+
+type kmlGeometry struct {
+	AltitudeMode string `xml:"altitudeMode"`
+	Coordinates  string `xml:"coordinates"`
+}
+
+// kmlCoordinates returns the KML coordinates of a position, i.e. longitude,
+// latitude and altitude.
+func kmlCoordinates(p tractive.TrackerPosition) string {
+	return strconv.FormatFloat(p.LatLong[1], 'f', -1, 64) + "," +
+		strconv.FormatFloat(p.LatLong[0], 'f', -1, 64) + "," +
+		strconv.Itoa(p.Alt)
+}
+
+func kmlTime(ts int64) string {
+	return time.Unix(ts, 0).UTC().Format(time.RFC3339)
+}
+
+// WriteKML writes a position history as a KML document named `name`. Every
+// segment of the history becomes a LineString placemark, or a Point placemark
+// if it has a single position. If points is true, every position also becomes
+// a Point placemark, whose extended data carry its speed, course, uncertainty
+// and sensor.
+func WriteKML(w io.Writer, name string, h *tractive.PositionHistory, points bool) error {
+	doc := kml{Xmlns: "http://www.opengis.net/kml/2.2"}
+	doc.Document.Name = name
+	for i, s := range h.Segments() {
+		coords := make([]string, 0, len(s))
+		for _, p := range s {
+			coords = append(coords, kmlCoordinates(p))
+		}
+		seg := kmlPlacemark{
+			Name: fmt.Sprintf("%s segment %d", name, i),
+			TimeSpan: &kmlTimeSpan{
+				Begin: kmlTime(s[0].Time),
+				End:   kmlTime(s[len(s)-1].Time),
+			},
+		}
+		geometry := &kmlGeometry{
+			AltitudeMode: "absolute",
+			Coordinates:  strings.Join(coords, " "),
+		}
+		// a LineString needs at least two positions.
+		if len(s) > 1 {
+			seg.LineString = geometry
+		} else {
+			seg.Point = geometry
+		}
+		doc.Document.Placemarks = append(doc.Document.Placemarks, seg)
+		if !points {
+			continue
+		}
+		for _, p := range s {
+			pm := kmlPlacemark{
+				TimeStamp: &kmlTimeStamp{When: kmlTime(p.Time)},
+				ExtendedData: &kmlExtendedData{Data: []kmlData{
+					{Name: "speed", Value: strconv.FormatFloat(p.Speed, 'f', -1, 64)},
+					{Name: "course", Value: strconv.Itoa(p.Course)},
+					{Name: "pos_uncertainty", Value: strconv.Itoa(p.PosUncertainty)},
+					{Name: "sensor_used", Value: p.SensorUsed},
+				}},
+				Point: &kmlGeometry{
+					AltitudeMode: "absolute",
+					Coordinates:  kmlCoordinates(p),
+				},
+			}
+			doc.Document.Placemarks = append(doc.Document.Placemarks, pm)
+		}
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return fmt.Errorf("failed to write KML header: %w", err)
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return fmt.Errorf("failed to encode KML: %w", err)
+	}
+	if _, err := io.WriteString(w, "\n"); err != nil {
+		return fmt.Errorf("failed to write KML: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package tractive
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	// DefaultHistoryWindow is the default duration of the windows a
+	// HistoryFetcher splits a time range into.
+	DefaultHistoryWindow = 24 * time.Hour
+	// DefaultHistoryConcurrency is the default number of windows a
+	// HistoryFetcher downloads concurrently.
+	DefaultHistoryConcurrency = 4
+)
+
+// maxBoundaryGap is the longest gap across a window boundary between two
+// segments for them to be considered a single segment cut by the boundary.
+const maxBoundaryGap = 15 * time.Minute
+
+// HistoryFetcher downloads the position history of a tracker over long time
+// ranges, by splitting them into windows that are fetched concurrently.
+type HistoryFetcher struct {
+	Tractive *Tractive
+	// Window is the duration of every request. If zero,
+	// DefaultHistoryWindow is used.
+	Window time.Duration
+	// Concurrency is the maximum number of concurrent requests. If zero,
+	// DefaultHistoryConcurrency is used.
+	Concurrency int
+}
+
+// HistoryChunk is the position history of a window, or the error that
+// occurred while fetching it.
+type HistoryChunk struct {
+	Start   time.Time
+	End     time.Time
+	History *PositionHistory
+	Err     error
+}
+
+// Fetch downloads the position history of a tracker between start and end.
+// Windows are delivered in chronological order on the returned channel,
+// which is closed when all of them have been delivered or the context is
+// done. Positions repeated at window boundaries are only delivered once. A
+// window that fails is delivered with its error, and the download
+// continues. The caller must either drain the channel or cancel the
+// context.
+func (f *HistoryFetcher) Fetch(ctx context.Context, trackerID string, start, end time.Time) <-chan HistoryChunk {
+	window := f.Window
+	if window <= 0 {
+		window = DefaultHistoryWindow
+	}
+	concurrency := f.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultHistoryConcurrency
+	}
+	out := make(chan HistoryChunk)
+	// pending holds the results of the in-flight windows, in order. Its
+	// capacity and sem bound the number of concurrent requests.
+	pending := make(chan chan HistoryChunk, concurrency)
+	sem := make(chan struct{}, concurrency)
+
+	go func() {
+		defer close(pending)
+		for ws := start; ws.Before(end); ws = ws.Add(window) {
+			we := ws.Add(window)
+			if we.After(end) {
+				we = end
+			}
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			res := make(chan HistoryChunk, 1)
+			select {
+			case pending <- res:
+			case <-ctx.Done():
+				<-sem
+				return
+			}
+			go func(ws, we time.Time) {
+				defer func() { <-sem }()
+				resp, err := f.Tractive.GetTrackerPositionsContext(ctx, trackerID, ws, we)
+				res <- HistoryChunk{Start: ws, End: we, History: resp.History(), Err: err}
+			}(ws, we)
+		}
+	}()
+
+	go func() {
+		defer close(out)
+		last := int64(math.MinInt64)
+		for res := range pending {
+			c := <-res
+			if c.Err == nil {
+				c.History, last = dropUntil(c.History, last)
+			}
+			select {
+			case out <- c:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// dropUntil removes from h the positions not more recent than `last`, and
+// returns the resulting history with the time of its most recent position.
+func dropUntil(h *PositionHistory, last int64) (*PositionHistory, int64) {
+	var segments [][]TrackerPosition
+	newLast := last
+	for _, s := range h.Segments() {
+		var seg []TrackerPosition
+		for _, p := range s {
+			if p.Time > last {
+				seg = append(seg, p)
+				if p.Time > newLast {
+					newLast = p.Time
+				}
+			}
+		}
+		segments = append(segments, seg)
+	}
+	return NewPositionHistory(segments), newLast
+}
+
+// crossesBoundary tells whether prev, the last segment before a window
+// boundary, and next, the first one after it, are a single segment cut by the
+// boundary.
+func crossesBoundary(prev, next []TrackerPosition, boundary time.Time) bool {
+	last, first := prev[len(prev)-1].Time, next[0].Time
+	return last <= boundary.Unix() && first >= boundary.Unix() &&
+		time.Duration(first-last)*time.Second <= maxBoundaryGap
+}
+
+func (t *Tractive) GetTrackerPositionHistory(trackerID string, start, end time.Time) (*PositionHistory, error) {
+	return t.GetTrackerPositionHistoryContext(context.Background(), trackerID, start, end)
+}
+
+// GetTrackerPositionHistoryContext downloads the position history of a
+// tracker over an arbitrarily long time range, using a HistoryFetcher with
+// the default settings. Segments cut by the boundary between two windows are
+// joined back.
+func (t *Tractive) GetTrackerPositionHistoryContext(ctx context.Context, trackerID string, start, end time.Time) (*PositionHistory, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	f := HistoryFetcher{Tractive: t}
+	var segments [][]TrackerPosition
+	for c := range f.Fetch(ctx, trackerID, start, end) {
+		if c.Err != nil {
+			return nil, fmt.Errorf("failed to get positions from %s to %s: %w", c.Start, c.End, c.Err)
+		}
+		next := c.History.Segments()
+		if len(segments) > 0 && len(next) > 0 && crossesBoundary(segments[len(segments)-1], next[0], c.Start) {
+			prev := segments[len(segments)-1]
+			segments[len(segments)-1] = append(append([]TrackerPosition(nil), prev...), next[0]...)
+			next = next[1:]
+		}
+		segments = append(segments, next...)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return NewPositionHistory(segments), nil
+}
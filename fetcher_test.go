@@ -0,0 +1,149 @@
+package tractive
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const testEpoch = 1700000000
+
+// track returns positions every five minutes from `from` to `to` hours
+// after testEpoch, included.
+func track(from, to float64) []TrackerPosition {
+	var seg []TrackerPosition
+	for ts := int64(testEpoch + from*3600); ts <= int64(testEpoch+to*3600); ts += 300 {
+		seg = append(seg, TrackerPosition{Time: ts, LatLong: [2]float64{48.2, 16.3 + float64(ts-testEpoch)/1e6}})
+	}
+	return seg
+}
+
+// newFakePositionsAPI serves the given segments, cut to the requested time
+// range with both ends included, like the Tractive API does.
+func newFakePositionsAPI(t *testing.T, segments [][]TrackerPosition, requests *atomic.Int32) *Tractive {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/4/tracker/T1/positions" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		requests.Add(1)
+		from, _ := strconv.ParseInt(r.URL.Query().Get("time_from"), 10, 64)
+		to, _ := strconv.ParseInt(r.URL.Query().Get("time_to"), 10, 64)
+		resp := [][]TrackerPosition{}
+		for _, s := range segments {
+			var seg []TrackerPosition
+			for _, p := range s {
+				if p.Time >= from && p.Time <= to {
+					seg = append(seg, p)
+				}
+			}
+			if len(seg) > 0 {
+				resp = append(resp, seg)
+			}
+		}
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			t.Errorf("failed to encode positions: %v", err)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	base, _ := url.Parse(srv.URL)
+	return New(WithBaseURL(base), WithToken("U1", "token"), WithLogger(testLogger{t}))
+}
+
+func TestGetTrackerPositionHistoryBoundaries(t *testing.T) {
+	segments := [][]TrackerPosition{
+		// crosses the boundary at 24h, and must not be split.
+		track(0, 30),
+		track(32, 40),
+		// a real break across the boundary at 48h, longer than
+		// maxBoundaryGap.
+		track(47, 47.9),
+		track(48.5, 50),
+	}
+	var requests atomic.Int32
+	tr := newFakePositionsAPI(t, segments, &requests)
+
+	h, err := tr.GetTrackerPositionHistory("T1", time.Unix(testEpoch, 0), time.Unix(testEpoch+72*3600, 0))
+	if err != nil {
+		t.Fatalf("GetTrackerPositionHistory failed: %v", err)
+	}
+	if n := requests.Load(); n != 3 {
+		t.Errorf("got %d requests, want one per 24h window", n)
+	}
+	got := h.Segments()
+	if len(got) != len(segments) {
+		t.Fatalf("got %d segments, want %d", len(got), len(segments))
+	}
+	for i := range segments {
+		if !reflect.DeepEqual(got[i], segments[i]) {
+			t.Errorf("segment %d: got %d positions from %d to %d, want %d from %d to %d", i,
+				len(got[i]), got[i][0].Time, got[i][len(got[i])-1].Time,
+				len(segments[i]), segments[i][0].Time, segments[i][len(segments[i])-1].Time)
+		}
+	}
+}
+
+func TestFetchDropsBoundaryDuplicates(t *testing.T) {
+	var requests atomic.Int32
+	tr := newFakePositionsAPI(t, [][]TrackerPosition{track(0, 10)}, &requests)
+	f := HistoryFetcher{Tractive: tr, Window: time.Hour, Concurrency: 3}
+
+	seen := make(map[int64]bool)
+	var chunks int
+	for c := range f.Fetch(context.Background(), "T1", time.Unix(testEpoch, 0), time.Unix(testEpoch+10*3600, 0)) {
+		if c.Err != nil {
+			t.Fatalf("chunk %s-%s failed: %v", c.Start, c.End, c.Err)
+		}
+		if want := time.Unix(testEpoch, 0).Add(time.Duration(chunks) * time.Hour); !c.Start.Equal(want) {
+			t.Errorf("chunk %d starts at %s, want %s", chunks, c.Start, want)
+		}
+		chunks++
+		for _, p := range c.History.All() {
+			if seen[p.Time] {
+				t.Errorf("position at %d delivered twice", p.Time)
+			}
+			seen[p.Time] = true
+		}
+	}
+	if chunks != 10 {
+		t.Errorf("got %d chunks, want 10", chunks)
+	}
+	if want := len(track(0, 10)); len(seen) != want {
+		t.Errorf("got %d positions, want %d", len(seen), want)
+	}
+}
+
+func TestDropUntil(t *testing.T) {
+	pos := func(ts ...int64) []TrackerPosition {
+		var s []TrackerPosition
+		for _, t := range ts {
+			s = append(s, TrackerPosition{Time: t})
+		}
+		return s
+	}
+	h := NewPositionHistory([][]TrackerPosition{pos(1, 2, 3), pos(4, 5)})
+	for _, tc := range []struct {
+		last     int64
+		want     [][]TrackerPosition
+		wantLast int64
+	}{
+		{math.MinInt64, [][]TrackerPosition{pos(1, 2, 3), pos(4, 5)}, 5},
+		{2, [][]TrackerPosition{pos(3), pos(4, 5)}, 5},
+		{3, [][]TrackerPosition{pos(4, 5)}, 5},
+		{5, nil, 5},
+		{10, nil, 10},
+	} {
+		got, gotLast := dropUntil(h, tc.last)
+		if !reflect.DeepEqual(got.Segments(), tc.want) || gotLast != tc.wantLast {
+			t.Errorf("dropUntil(%d) = %v, %d, want %v, %d", tc.last, got.Segments(), gotLast, tc.want, tc.wantLast)
+		}
+	}
+}
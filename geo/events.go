@@ -0,0 +1,138 @@
+package geo
+
+import (
+	"github.com/insomniacslk/tractive"
+)
+
+// Classification is the result of testing an uncertain position against a
+// zone.
+type Classification int
+
+const (
+	// Outside means that the position is outside the zone, even
+	// accounting for its uncertainty.
+	Outside Classification = iota
+	// Maybe means that the position may be inside or outside the zone
+	// depending on its uncertainty.
+	Maybe
+	// Inside means that the position is inside the zone, even accounting
+	// for its uncertainty.
+	Inside
+)
+
+func (c Classification) String() string {
+	switch c {
+	case Outside:
+		return "outside"
+	case Maybe:
+		return "maybe"
+	case Inside:
+		return "inside"
+	}
+	return "unknown"
+}
+
+// Classify tells whether a point with the given uncertainty in meters is
+// definitely inside, definitely outside, or maybe inside a zone.
+func Classify(z Zone, p Point, uncertainty float64) Classification {
+	d := z.SignedDistance(p)
+	switch {
+	case d <= -uncertainty:
+		return Inside
+	case d > uncertainty:
+		return Outside
+	}
+	return Maybe
+}
+
+// ClassifyPosition classifies a position using its reported uncertainty.
+func ClassifyPosition(z Zone, p tractive.TrackerPosition) Classification {
+	return Classify(z, PositionPoint(p), float64(p.PosUncertainty))
+}
+
+// EventKind is the kind of a zone event.
+type EventKind string
+
+const (
+	Enter EventKind = "enter"
+	Exit  EventKind = "exit"
+)
+
+// Event is the crossing of the border of a zone.
+type Event struct {
+	Kind EventKind
+	Zone Zone
+	// Position is the first position on the new side of the border.
+	Position tractive.TrackerPosition
+}
+
+// EventOptions controls the hysteresis of DetectEvents.
+type EventOptions struct {
+	// Margin is added to the uncertainty of every position, in meters, so
+	// that positions close to the border don't count as crossings.
+	Margin float64
+	// Confirmations is the number of consecutive positions definitely on
+	// the new side of the border needed to report a crossing. Values lower
+	// than 1 are treated as 1.
+	Confirmations int
+}
+
+// DefaultEventOptions ignore single outliers and positions within 10 meters
+// of the border.
+var DefaultEventOptions = EventOptions{
+	Margin:        10,
+	Confirmations: 2,
+}
+
+// DetectEvents returns the enter and exit events of a time-ordered position
+// series over a set of zones, in chronological order. Positions that may be
+// on either side of a border are ignored. The side of a border where the
+// series starts is not reported as an event.
+func DetectEvents(positions []tractive.TrackerPosition, zones []Zone, opts EventOptions) []Event {
+	confirmations := opts.Confirmations
+	if confirmations < 1 {
+		confirmations = 1
+	}
+	type zoneState struct {
+		known  bool
+		inside bool
+		// pending counts the consecutive positions on the other side of
+		// the border, starting from first.
+		pending int
+		first   tractive.TrackerPosition
+	}
+	states := make([]zoneState, len(zones))
+	var events []Event
+	for _, p := range positions {
+		for i, z := range zones {
+			c := Classify(z, PositionPoint(p), float64(p.PosUncertainty)+opts.Margin)
+			if c == Maybe {
+				continue
+			}
+			s := &states[i]
+			inside := c == Inside
+			if !s.known {
+				s.known, s.inside = true, inside
+				continue
+			}
+			if inside == s.inside {
+				s.pending = 0
+				continue
+			}
+			if s.pending == 0 {
+				s.first = p
+			}
+			s.pending++
+			if s.pending < confirmations {
+				continue
+			}
+			kind := Exit
+			if inside {
+				kind = Enter
+			}
+			events = append(events, Event{Kind: kind, Zone: z, Position: s.first})
+			s.inside, s.pending = inside, 0
+		}
+	}
+	return events
+}
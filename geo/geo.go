@@ -0,0 +1,139 @@
+// Package geo evaluates positions against zones offline: distances,
+// point-in-zone tests that account for the uncertainty of a fix, and
+// enter/exit events over a position series.
+package geo
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/insomniacslk/tractive"
+)
+
+// EarthRadius is the mean radius of the Earth, in meters.
+const EarthRadius = 6371008.8
+
+const rad = math.Pi / 180
+
+// Point is a latitude and a longitude, in degrees.
+type Point struct {
+	Lat float64
+	Lon float64
+}
+
+// PositionPoint returns the coordinates of a position.
+func PositionPoint(p tractive.TrackerPosition) Point {
+	return Point{Lat: p.LatLong[0], Lon: p.LatLong[1]}
+}
+
+// Haversine returns the great-circle distance in meters between two points.
+func Haversine(a, b Point) float64 {
+	dLat := (b.Lat - a.Lat) * rad
+	dLon := (b.Lon - a.Lon) * rad
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(a.Lat*rad)*math.Cos(b.Lat*rad)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * EarthRadius * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// Zone is an area on the Earth's surface.
+type Zone interface {
+	ZoneName() string
+	Contains(p Point) bool
+	// SignedDistance returns the distance in meters between a point and
+	// the border of the zone: negative if the point is inside the zone,
+	// positive if it is outside.
+	SignedDistance(p Point) float64
+}
+
+// Circle is a zone made of the points within Radius meters of Center.
+type Circle struct {
+	Name   string
+	Center Point
+	Radius float64
+}
+
+func (c *Circle) ZoneName() string {
+	return c.Name
+}
+
+func (c *Circle) Contains(p Point) bool {
+	return Haversine(c.Center, p) <= c.Radius
+}
+
+func (c *Circle) SignedDistance(p Point) float64 {
+	return Haversine(c.Center, p) - c.Radius
+}
+
+// Polygon is a zone delimited by a closed polygon. Edges are straight lines
+// in the latitude/longitude plane, which is accurate enough for zones up to
+// a few kilometers wide, away from the poles and the antimeridian.
+type Polygon struct {
+	Name     string
+	Vertices []Point
+}
+
+func (pg *Polygon) ZoneName() string {
+	return pg.Name
+}
+
+// Contains tells whether a point is inside the polygon, using the
+// even-odd rule.
+func (pg *Polygon) Contains(p Point) bool {
+	inside := false
+	n := len(pg.Vertices)
+	for i, j := 0, n-1; i < n; j, i = i, i+1 {
+		a, b := pg.Vertices[i], pg.Vertices[j]
+		if (a.Lat > p.Lat) != (b.Lat > p.Lat) &&
+			p.Lon < (b.Lon-a.Lon)*(p.Lat-a.Lat)/(b.Lat-a.Lat)+a.Lon {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+func (pg *Polygon) SignedDistance(p Point) float64 {
+	// project the vertices on a plane tangent to the Earth at p, in
+	// meters, and find the closest edge.
+	kx := EarthRadius * rad * math.Cos(p.Lat*rad)
+	ky := EarthRadius * rad
+	d := math.Inf(1)
+	n := len(pg.Vertices)
+	for i, j := 0, n-1; i < n; j, i = i, i+1 {
+		ax, ay := (pg.Vertices[i].Lon-p.Lon)*kx, (pg.Vertices[i].Lat-p.Lat)*ky
+		bx, by := (pg.Vertices[j].Lon-p.Lon)*kx, (pg.Vertices[j].Lat-p.Lat)*ky
+		d = math.Min(d, distanceToSegment(ax, ay, bx, by))
+	}
+	if pg.Contains(p) {
+		return -d
+	}
+	return d
+}
+
+// distanceToSegment returns the distance between the origin and the
+// segment from (ax, ay) to (bx, by).
+func distanceToSegment(ax, ay, bx, by float64) float64 {
+	dx, dy := bx-ax, by-ay
+	t := 0.0
+	if l := dx*dx + dy*dy; l > 0 {
+		t = math.Max(0, math.Min(1, -(ax*dx+ay*dy)/l))
+	}
+	return math.Hypot(ax+t*dx, ay+t*dy)
+}
+
+// ZoneFromGeofence returns the zone of a Tractive geofence.
+func ZoneFromGeofence(g *tractive.Geofence) (Zone, error) {
+	if err := g.Validate(); err != nil {
+		return nil, err
+	}
+	points := make([]Point, 0, len(g.Coords))
+	for _, c := range g.Coords {
+		points = append(points, Point{Lat: c[0], Lon: c[1]})
+	}
+	switch g.Shape {
+	case tractive.GeofenceShapeCircle:
+		return &Circle{Name: g.Name, Center: points[0], Radius: float64(g.Radius)}, nil
+	case tractive.GeofenceShapePolygon:
+		return &Polygon{Name: g.Name, Vertices: points}, nil
+	}
+	return nil, fmt.Errorf("unsupported geofence shape %q", g.Shape)
+}
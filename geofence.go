@@ -0,0 +1,134 @@
+package tractive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Geofence shapes.
+const (
+	GeofenceShapeCircle  = "CIRCLE"
+	GeofenceShapePolygon = "POLYGON"
+)
+
+// Geofence types: a safe zone alerts when the pet leaves it, a danger zone
+// when the pet enters it.
+const (
+	GeofenceTypeSafe   = "SAFE"
+	GeofenceTypeDanger = "DANGER"
+)
+
+type GetGeofencesResponse []struct {
+	Envelope
+}
+
+// GeofenceAlertSettings selects the notifications sent for a geofence.
+type GeofenceAlertSettings struct {
+	OnEnter bool `json:"on_enter"`
+	OnExit  bool `json:"on_exit"`
+}
+
+// Geofence is a zone monitored by a tracker. A circle has a single
+// coordinate, its center, and a radius. A polygon has at least three
+// coordinates, its vertices. Coordinates are latitude and longitude.
+type Geofence struct {
+	Envelope
+	Name      string                `json:"name"`
+	FenceType string                `json:"fence_type"`
+	Shape     string                `json:"shape"`
+	Coords    [][2]float64          `json:"coords"`
+	Radius    int                   `json:"radius,omitempty"`
+	Active    bool                  `json:"active"`
+	Alerts    GeofenceAlertSettings `json:"alerts"`
+	ReadOnly  bool                  `json:"read_only,omitempty"`
+}
+
+// Validate checks that the type of the geofence is known, if set, and that
+// its shape is consistent.
+func (g *Geofence) Validate() error {
+	if g.Name == "" {
+		return errors.New("geofence has no name")
+	}
+	switch g.FenceType {
+	case "", GeofenceTypeSafe, GeofenceTypeDanger:
+	default:
+		return fmt.Errorf("geofence %q has unknown type %q", g.Name, g.FenceType)
+	}
+	switch g.Shape {
+	case GeofenceShapeCircle:
+		if len(g.Coords) != 1 {
+			return fmt.Errorf("circle geofence %q must have exactly one coordinate, got %d", g.Name, len(g.Coords))
+		}
+		if g.Radius <= 0 {
+			return fmt.Errorf("circle geofence %q must have a positive radius", g.Name)
+		}
+	case GeofenceShapePolygon:
+		if len(g.Coords) < 3 {
+			return fmt.Errorf("polygon geofence %q must have at least three coordinates, got %d", g.Name, len(g.Coords))
+		}
+	default:
+		return fmt.Errorf("geofence %q has unknown shape %q", g.Name, g.Shape)
+	}
+	return nil
+}
+
+func (t *Tractive) GetGeofences(trackerID string) (*GetGeofencesResponse, error) {
+	return t.GetGeofencesContext(context.Background(), trackerID)
+}
+
+func (t *Tractive) GetGeofencesContext(ctx context.Context, trackerID string) (*GetGeofencesResponse, error) {
+	u := t.tractiveURL("/4/tracker/" + trackerID + "/geofences")
+	return callAPI[GetGeofencesResponse](ctx, t, http.MethodGet, u, nil)
+}
+
+func (t *Tractive) GetGeofence(geofenceID string) (*Geofence, error) {
+	return t.GetGeofenceContext(context.Background(), geofenceID)
+}
+
+func (t *Tractive) GetGeofenceContext(ctx context.Context, geofenceID string) (*Geofence, error) {
+	u := t.tractiveURL("/4/geofence/" + geofenceID)
+	return callAPI[Geofence](ctx, t, http.MethodGet, u, nil)
+}
+
+func (t *Tractive) CreateGeofence(trackerID string, g *Geofence) (*Geofence, error) {
+	return t.CreateGeofenceContext(context.Background(), trackerID, g)
+}
+
+// CreateGeofenceContext creates a geofence on a tracker, and returns it as
+// stored by Tractive.
+func (t *Tractive) CreateGeofenceContext(ctx context.Context, trackerID string, g *Geofence) (*Geofence, error) {
+	if err := g.Validate(); err != nil {
+		return nil, err
+	}
+	u := t.tractiveURL("/4/tracker/" + trackerID + "/geofences")
+	return callAPI[Geofence](ctx, t, http.MethodPost, u, g)
+}
+
+func (t *Tractive) UpdateGeofence(g *Geofence) (*Geofence, error) {
+	return t.UpdateGeofenceContext(context.Background(), g)
+}
+
+// UpdateGeofenceContext replaces the geofence with the same ID, and returns
+// it as stored by Tractive.
+func (t *Tractive) UpdateGeofenceContext(ctx context.Context, g *Geofence) (*Geofence, error) {
+	if g.ID == "" {
+		return nil, errors.New("geofence has no ID")
+	}
+	if err := g.Validate(); err != nil {
+		return nil, err
+	}
+	u := t.tractiveURL("/4/geofence/" + g.ID)
+	return callAPI[Geofence](ctx, t, http.MethodPut, u, g)
+}
+
+func (t *Tractive) DeleteGeofence(geofenceID string) error {
+	return t.DeleteGeofenceContext(context.Background(), geofenceID)
+}
+
+func (t *Tractive) DeleteGeofenceContext(ctx context.Context, geofenceID string) error {
+	u := t.tractiveURL("/4/geofence/" + geofenceID)
+	_, err := callAPI[struct{}](ctx, t, http.MethodDelete, u, nil)
+	return err
+}
@@ -0,0 +1,101 @@
+package tractive
+
+import (
+	"sort"
+)
+
+// PositionHistory is a validated position history: a list of segments
+// ordered by start time, each made of positions ordered by time. Empty
+// segments and duplicate fixes are dropped.
+type PositionHistory struct {
+	segments [][]TrackerPosition
+	len      int
+}
+
+// NewPositionHistory returns a PositionHistory from raw json_segments
+// segments, which are not modified.
+func NewPositionHistory(segments [][]TrackerPosition) *PositionHistory {
+	var h PositionHistory
+	for _, s := range segments {
+		if len(s) == 0 {
+			continue
+		}
+		seg := make([]TrackerPosition, len(s))
+		copy(seg, s)
+		sort.SliceStable(seg, func(i, j int) bool { return seg[i].Time < seg[j].Time })
+		// drop duplicate fixes, which are now adjacent.
+		uniq := seg[:1]
+		for _, p := range seg[1:] {
+			if p != uniq[len(uniq)-1] {
+				uniq = append(uniq, p)
+			}
+		}
+		h.segments = append(h.segments, uniq)
+		h.len += len(uniq)
+	}
+	sort.SliceStable(h.segments, func(i, j int) bool { return h.segments[i][0].Time < h.segments[j][0].Time })
+	return &h
+}
+
+// History returns the validated position history of the response. It is
+// safe to call on a nil response.
+func (r *GetTrackerPositionsResponse) History() *PositionHistory {
+	if r == nil {
+		return NewPositionHistory(nil)
+	}
+	return NewPositionHistory(*r)
+}
+
+// Segments returns the segments of the history. The caller must not modify
+// them.
+func (h *PositionHistory) Segments() [][]TrackerPosition {
+	return h.segments
+}
+
+// Len returns the number of positions in the history.
+func (h *PositionHistory) Len() int {
+	return h.len
+}
+
+// All returns all the positions of the history, ordered by time.
+func (h *PositionHistory) All() []TrackerPosition {
+	all := make([]TrackerPosition, 0, h.len)
+	for _, s := range h.segments {
+		all = append(all, s...)
+	}
+	// segments are ordered by start time, but they may overlap.
+	sort.SliceStable(all, func(i, j int) bool { return all[i].Time < all[j].Time })
+	return all
+}
+
+// Range calls fn for every position, ordered by time, until fn returns
+// false.
+func (h *PositionHistory) Range(fn func(p TrackerPosition) bool) {
+	for _, p := range h.All() {
+		if !fn(p) {
+			return
+		}
+	}
+}
+
+// First returns the oldest position, if any.
+func (h *PositionHistory) First() (TrackerPosition, bool) {
+	if h.len == 0 {
+		return TrackerPosition{}, false
+	}
+	return h.segments[0][0], true
+}
+
+// Last returns the most recent position, if any.
+func (h *PositionHistory) Last() (TrackerPosition, bool) {
+	if h.len == 0 {
+		return TrackerPosition{}, false
+	}
+	var last TrackerPosition
+	for _, s := range h.segments {
+		if p := s[len(s)-1]; p.Time >= last.Time {
+			last = p
+		}
+	}
+	return last, true
+}
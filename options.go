@@ -0,0 +1,141 @@
+package tractive
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Logger is the interface used by the client to log. *logrus.Logger and
+// *logrus.Entry implement it.
+type Logger interface {
+	Debugf(format string, args ...interface{})
+	Infof(format string, args ...interface{})
+	Warningf(format string, args ...interface{})
+}
+
+// Option configures a Tractive client.
+type Option func(*Tractive)
+
+// New returns a Tractive client configured with the given options. Unless
+// WithToken is used or a session is resumed from a token store, the client
+// has to authenticate before calling the API, see Authenticate.
+func New(opts ...Option) *Tractive {
+	var t Tractive
+	for _, opt := range opts {
+		opt(&t)
+	}
+	t.resumeSession()
+	return &t
+}
+
+// WithHTTPClient sets the HTTP client used to send requests. The default is
+// DefaultHTTPClient.
+func WithHTTPClient(c *http.Client) Option {
+	return func(t *Tractive) {
+		t.httpClient = c
+	}
+}
+
+// WithTransport sets the transport of the HTTP client used to send requests,
+// keeping the default timeout.
+func WithTransport(rt http.RoundTripper) Option {
+	return func(t *Tractive) {
+		t.httpClient = &http.Client{
+			Transport: rt,
+			Timeout:   DefaultTimeout,
+		}
+	}
+}
+
+// WithBaseURL sets the URL of the Tractive API. The default is
+// https://graph.tractive.com .
+func WithBaseURL(u *url.URL) Option {
+	return func(t *Tractive) {
+		t.baseURL = u
+	}
+}
+
+// WithClientID sets the client ID sent in the X-Tractive-Client header. The
+// default is ClientID.
+func WithClientID(clientID string) Option {
+	return func(t *Tractive) {
+		t.clientID = clientID
+	}
+}
+
+// WithUserAgent sets the User-Agent header of the requests.
+func WithUserAgent(ua string) Option {
+	return func(t *Tractive) {
+		t.userAgent = ua
+	}
+}
+
+// WithLogger sets the logger. The default is the logrus standard logger.
+func WithLogger(l Logger) Option {
+	return func(t *Tractive) {
+		t.logger = l
+	}
+}
+
+// WithRetryPolicy sets the retry policy. The default is DefaultRetryPolicy.
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(t *Tractive) {
+		t.RetryPolicy = &p
+	}
+}
+
+// WithRateLimiter sets a client-side rate limiter.
+func WithRateLimiter(r *RateLimiter) Option {
+	return func(t *Tractive) {
+		t.RateLimiter = r
+	}
+}
+
+// WithCredentials sets the username and password used to authenticate.
+func WithCredentials(username, password string) Option {
+	return func(t *Tractive) {
+		t.Username = username
+		t.Password = password
+	}
+}
+
+// WithToken sets an existing access token and the ID of the user it belongs
+// to, so that the client can be used without authenticating.
+func WithToken(userID, token string) Option {
+	return func(t *Tractive) {
+		t.UserID = userID
+		t.Token = token
+	}
+}
+
+func (t *Tractive) getHTTPClient() *http.Client {
+	if t.httpClient != nil {
+		return t.httpClient
+	}
+	return DefaultHTTPClient
+}
+
+func (t *Tractive) getLogger() Logger {
+	if t.logger != nil {
+		return t.logger
+	}
+	return logrus.StandardLogger()
+}
+
+func (t *Tractive) getClientID() string {
+	if t.clientID != "" {
+		return t.clientID
+	}
+	return ClientID
+}
+
+// debugEnabled tells whether the logger emits debug messages. Loggers that
+// can't tell are assumed to.
+func (t *Tractive) debugEnabled() bool {
+	if l, ok := t.getLogger().(interface{ IsLevelEnabled(logrus.Level) bool }); ok {
+		return l.IsLevelEnabled(logrus.DebugLevel)
+	}
+	return true
+}
@@ -1,8 +1,9 @@
 package tractive
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
+	"net/http"
 
 	"github.com/insomniacslk/xjson"
 )
@@ -55,31 +56,44 @@ type PetResponse struct {
 }
 
 func (t *Tractive) GetPets() (*PetsResponse, error) {
-	u := getTractiveURL()
-	u.Path = "/4/user/" + t.UserID + "/trackable_objects"
-	body, err := tractiveRequest("GET", u, t.Token)
-	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
-	}
-	var resp PetsResponse
-	if err := json.Unmarshal(body, &resp); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal json response: %w", err)
-	}
-	return &resp, nil
+	return t.GetPetsContext(context.Background())
+}
 
+func (t *Tractive) GetPetsContext(ctx context.Context) (*PetsResponse, error) {
+	u := t.tractiveURL("/4/user/" + t.UserID + "/trackable_objects")
+	return callAPI[PetsResponse](ctx, t, http.MethodGet, u, nil)
 }
 
 func (t *Tractive) GetPet(petID string) (*PetResponse, error) {
-	u := getTractiveURL()
-	u.Path = "/4/trackable_object/" + petID
-	body, err := tractiveRequest("GET", u, t.Token)
+	return t.GetPetContext(context.Background(), petID)
+}
+
+func (t *Tractive) GetPetContext(ctx context.Context, petID string) (*PetResponse, error) {
+	u := t.tractiveURL("/4/trackable_object/" + petID)
+	return callAPI[PetResponse](ctx, t, http.MethodGet, u, nil)
+}
+
+func (t *Tractive) GetPetsByTracker() (map[string]*PetResponse, error) {
+	return t.GetPetsByTrackerContext(context.Background())
+}
+
+// GetPetsByTrackerContext returns the user's pets, keyed by the ID of their
+// tracker. Pets without a tracker are left out.
+func (t *Tractive) GetPetsByTrackerContext(ctx context.Context) (map[string]*PetResponse, error) {
+	pets, err := t.GetPetsContext(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
+		return nil, err
 	}
-	var resp PetResponse
-	if err := json.Unmarshal(body, &resp); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal json response: %w", err)
+	byTracker := make(map[string]*PetResponse, len(*pets))
+	for _, p := range *pets {
+		pet, err := t.GetPetContext(ctx, p.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get pet %q: %w", p.ID, err)
+		}
+		if pet.DeviceID == "" {
+			continue
+		}
+		byTracker[pet.DeviceID] = pet
 	}
-	return &resp, nil
-
+	return byTracker, nil
 }
@@ -0,0 +1,34 @@
+package tractive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestIsRetryable(t *testing.T) {
+	for _, tc := range []struct {
+		method string
+		err    error
+		want   bool
+	}{
+		{http.MethodGet, &APIError{StatusCode: http.StatusTooManyRequests}, true},
+		{http.MethodPost, &APIError{StatusCode: http.StatusTooManyRequests}, true},
+		{http.MethodGet, &APIError{StatusCode: http.StatusBadGateway}, true},
+		{http.MethodPut, &APIError{StatusCode: http.StatusServiceUnavailable}, true},
+		{http.MethodDelete, &APIError{StatusCode: http.StatusGatewayTimeout}, true},
+		// the server may have applied the request before failing.
+		{http.MethodPost, &APIError{StatusCode: http.StatusBadGateway}, false},
+		{http.MethodPost, &APIError{StatusCode: http.StatusGatewayTimeout}, false},
+		{http.MethodGet, &APIError{StatusCode: http.StatusNotFound}, false},
+		{http.MethodGet, errors.New("connection reset"), true},
+		{http.MethodPost, errors.New("connection reset"), false},
+		{http.MethodGet, fmt.Errorf("request failed: %w", context.Canceled), false},
+	} {
+		if got := isRetryable(tc.method, tc.err); got != tc.want {
+			t.Errorf("isRetryable(%s, %v) = %t, want %t", tc.method, tc.err, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package tractive
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/insomniacslk/tractive/internal/fileutil"
+)
+
+// Session is an authenticated Tractive session, as persisted by a
+// TokenStore.
+type Session struct {
+	Username  string    `json:"username"`
+	UserID    string    `json:"user_id"`
+	ClientID  string    `json:"client_id"`
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Expired tells whether the session's token is expired, or about to.
+func (s *Session) Expired() bool {
+	return !s.ExpiresAt.IsZero() && !time.Now().Before(s.ExpiresAt.Add(-TokenExpiryMargin))
+}
+
+// TokenStore persists a Session across runs.
+type TokenStore interface {
+	// Load returns the stored session, or nil if there is none.
+	Load() (*Session, error)
+	// Save stores the session, replacing any previous one.
+	Save(*Session) error
+	// Clear removes the stored session, if any.
+	Clear() error
+}
+
+// WithTokenStore sets the store where the session is persisted. New resumes
+// the stored session if it has not expired, and every new token obtained by
+// the client is saved to the store.
+func WithTokenStore(s TokenStore) Option {
+	return func(t *Tractive) {
+		t.tokenStore = s
+	}
+}
+
+// Session returns the current session of the client.
+func (t *Tractive) Session() *Session {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.session()
+}
+
+// Logout forgets the token and clears the token store, if any.
+func (t *Tractive) Logout() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.Token = ""
+	t.TokenExpiresAt = time.Time{}
+	if t.tokenStore == nil {
+		return nil
+	}
+	return t.tokenStore.Clear()
+}
+
+func (t *Tractive) session() *Session {
+	return &Session{
+		Username:  t.Username,
+		UserID:    t.UserID,
+		ClientID:  t.ClientID,
+		Token:     t.Token,
+		ExpiresAt: t.TokenExpiresAt,
+	}
+}
+
+// resumeSession loads the stored session, if any. Sessions that are expired,
+// or that belong to a different user than the configured one, are ignored.
+func (t *Tractive) resumeSession() {
+	if t.tokenStore == nil || t.Token != "" {
+		return
+	}
+	s, err := t.tokenStore.Load()
+	if err != nil {
+		t.getLogger().Warningf("Failed to load stored session: %v", err)
+		return
+	}
+	if s == nil || s.Expired() || (t.Username != "" && s.Username != t.Username) {
+		return
+	}
+	t.Username = s.Username
+	t.UserID = s.UserID
+	t.ClientID = s.ClientID
+	t.Token = s.Token
+	t.TokenExpiresAt = s.ExpiresAt
+}
+
+// saveSession persists the current session, if a token store is set. It
+// must be called with t.mu held.
+func (t *Tractive) saveSession() {
+	if t.tokenStore == nil {
+		return
+	}
+	if err := t.tokenStore.Save(t.session()); err != nil {
+		t.getLogger().Warningf("Failed to save session: %v", err)
+	}
+}
+
+// FileTokenStore is a TokenStore backed by a JSON file, readable only by its
+// owner.
+type FileTokenStore struct {
+	Path string
+}
+
+// NewFileTokenStore returns a FileTokenStore backed by the given file. If
+// path is empty, DefaultTokenStorePath is used.
+func NewFileTokenStore(path string) (*FileTokenStore, error) {
+	if path == "" {
+		var err error
+		path, err = DefaultTokenStorePath()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &FileTokenStore{Path: path}, nil
+}
+
+// DefaultTokenStorePath returns the path of the session file in the user's
+// configuration directory, e.g. $XDG_CONFIG_HOME/tractive/session.json .
+func DefaultTokenStorePath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get user config dir: %w", err)
+	}
+	return filepath.Join(dir, "tractive", "session.json"), nil
+}
+
+func (f *FileTokenStore) Load() (*Session, error) {
+	data, err := os.ReadFile(f.Path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read session file: %w", err)
+	}
+	var s Session
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal session file: %w", err)
+	}
+	return &s, nil
+}
+
+func (f *FileTokenStore) Save(s *Session) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("failed to marshal session: %w", err)
+	}
+	if err := fileutil.WriteAtomic(f.Path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write session file: %w", err)
+	}
+	return nil
+}
+
+func (f *FileTokenStore) Clear() error {
+	if err := os.Remove(f.Path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to remove session file: %w", err)
+	}
+	return nil
+}
@@ -1,8 +1,11 @@
 package tractive
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
 	"strconv"
 	"time"
 
@@ -38,6 +41,65 @@ type GetTrackerResponse struct {
 	PrioritizedZoneEnteredAt  *xjson.TimeUnix `json:"prioritized_zone_entered_at"`
 }
 
+type GetTrackerHardwareResponse struct {
+	Envelope
+	Time              xjson.TimeUnix `json:"time"`
+	BatteryLevel      int            `json:"battery_level"`
+	BatteryState      string         `json:"battery_state"`
+	ChargingState     string         `json:"charging_state"`
+	TemperatureState  string         `json:"temperature_state"`
+	ClipMountedState  string         `json:"clip_mounted_state"`
+	HwStatus          *string        `json:"hw_status"`
+	PowerSavingZoneID *string        `json:"power_saving_zone_id"`
+	ReportID          string         `json:"report_id"`
+}
+
+func (h *GetTrackerHardwareResponse) String() string {
+	return fmt.Sprintf("[%s] battery_level=%d%% battery_state=%s charging_state=%s temperature_state=%s clip_mounted_state=%s power_saving=%t", time.Time(h.Time), h.BatteryLevel, h.BatteryState, h.ChargingState, h.TemperatureState, h.ClipMountedState, h.PowerSavingZoneID != nil)
+}
+
+// LiveFixMaxAge is the maximum age of a location fix for it to be considered
+// live.
+const LiveFixMaxAge = 2 * time.Minute
+
+type GetTrackerLocationResponse struct {
+	Envelope
+	Time              int64      `json:"time"`
+	TimeReceived      int64      `json:"time_rcvd"`
+	LatLong           [2]float64 `json:"latlong"`
+	Altitude          int        `json:"altitude"`
+	Speed             float64    `json:"speed"`
+	Course            int        `json:"course"`
+	PosUncertainty    int        `json:"pos_uncertainty"`
+	SensorUsed        string     `json:"sensor_used"`
+	PowerSavingZoneID *string    `json:"power_saving_zone_id"`
+	ReportID          string     `json:"report_id"`
+}
+
+// Live tells whether the fix is recent, i.e. it is no older than
+// LiveFixMaxAge.
+func (l *GetTrackerLocationResponse) Live() bool {
+	return time.Since(time.Unix(l.Time, 0)) <= LiveFixMaxAge
+}
+
+// Position returns the fix as a TrackerPosition.
+func (l *GetTrackerLocationResponse) Position() TrackerPosition {
+	return TrackerPosition{
+		Time:           l.Time,
+		LatLong:        l.LatLong,
+		Alt:            l.Altitude,
+		Speed:          l.Speed,
+		Course:         l.Course,
+		PosUncertainty: l.PosUncertainty,
+		SensorUsed:     l.SensorUsed,
+	}
+}
+
+func (l *GetTrackerLocationResponse) String() string {
+	p := l.Position()
+	return fmt.Sprintf("%s live=%t", p.String(), l.Live())
+}
+
 type GetTrackerPositionsResponse [][]TrackerPosition
 
 type TrackerPosition struct {
@@ -50,54 +112,146 @@ type TrackerPosition struct {
 	SensorUsed     string     `json:"sensor_used"`
 }
 
+// UnmarshalJSON decodes a position, accepting both the v3 and the v4 names
+// of the altitude field.
+func (p *TrackerPosition) UnmarshalJSON(b []byte) error {
+	type position TrackerPosition
+	var v struct {
+		position
+		Altitude *int `json:"altitude"`
+	}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	*p = TrackerPosition(v.position)
+	if v.Altitude != nil {
+		p.Alt = *v.Altitude
+	}
+	return nil
+}
+
+// UnmarshalJSON decodes a position history, accepting both the
+// json_segments format, i.e. a list of segments, and a flat list of
+// positions, which becomes a single segment.
+func (r *GetTrackerPositionsResponse) UnmarshalJSON(b []byte) error {
+	var segments [][]TrackerPosition
+	if err := json.Unmarshal(b, &segments); err == nil {
+		*r = segments
+		return nil
+	}
+	var positions []TrackerPosition
+	if err := json.Unmarshal(b, &positions); err != nil {
+		return err
+	}
+	*r = nil
+	if len(positions) > 0 {
+		*r = GetTrackerPositionsResponse{positions}
+	}
+	return nil
+}
+
 func (p *TrackerPosition) String() string {
 	return fmt.Sprintf("[%s] latitude=%.3f longitude=%.3f altitude=%d speed=%.3f course=%d pos_uncertainty=%d sensor_used=%s", time.Unix(p.Time, 0), p.LatLong[0], p.LatLong[1], p.Alt, p.Speed, p.Course, p.PosUncertainty, p.SensorUsed)
 }
 
 func (t *Tractive) GetAllTrackers() (*GetAllTrackersResponse, error) {
-	u := getTractiveURL()
-	u.Path = "/4/user/" + t.UserID + "/trackers"
-	body, err := tractiveRequest("GET", u, t.Token)
-	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
-	}
-	var resp GetAllTrackersResponse
-	if err := json.Unmarshal(body, &resp); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal json response: %w", err)
-	}
-	return &resp, nil
+	return t.GetAllTrackersContext(context.Background())
+}
+
+func (t *Tractive) GetAllTrackersContext(ctx context.Context) (*GetAllTrackersResponse, error) {
+	u := t.tractiveURL("/4/user/" + t.UserID + "/trackers")
+	return callAPI[GetAllTrackersResponse](ctx, t, http.MethodGet, u, nil)
 }
 
 func (t *Tractive) GetTracker(trackerID string) (*GetTrackerResponse, error) {
-	u := getTractiveURL()
-	u.Path = "/4/tracker/" + trackerID
-	body, err := tractiveRequest("GET", u, t.Token)
-	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
+	return t.GetTrackerContext(context.Background(), trackerID)
+}
+
+func (t *Tractive) GetTrackerContext(ctx context.Context, trackerID string) (*GetTrackerResponse, error) {
+	u := t.tractiveURL("/4/tracker/" + trackerID)
+	return callAPI[GetTrackerResponse](ctx, t, http.MethodGet, u, nil)
+}
+
+func (t *Tractive) GetTrackerHardware(trackerID string) (*GetTrackerHardwareResponse, error) {
+	return t.GetTrackerHardwareContext(context.Background(), trackerID)
+}
+
+func (t *Tractive) GetTrackerHardwareContext(ctx context.Context, trackerID string) (*GetTrackerHardwareResponse, error) {
+	u := t.tractiveURL("/4/device_hw_report/" + trackerID)
+	return callAPI[GetTrackerHardwareResponse](ctx, t, http.MethodGet, u, nil)
+}
+
+func (t *Tractive) GetTrackerLocation(trackerID string) (*GetTrackerLocationResponse, error) {
+	return t.GetTrackerLocationContext(context.Background(), trackerID)
+}
+
+func (t *Tractive) GetTrackerLocationContext(ctx context.Context, trackerID string) (*GetTrackerLocationResponse, error) {
+	u := t.tractiveURL("/4/device_pos_report/" + trackerID)
+	return callAPI[GetTrackerLocationResponse](ctx, t, http.MethodGet, u, nil)
+}
+
+func (t *Tractive) GetTrackerPositions(trackerID string, start, end time.Time) (*GetTrackerPositionsResponse, error) {
+	return t.GetTrackerPositionsContext(context.Background(), trackerID, start, end)
+}
+
+// GetTrackerPositionsContext returns the position history of a tracker
+// between start and end. It uses the v4 API, falling back to v3 if v4 is
+// not available; the first version that works is remembered.
+func (t *Tractive) GetTrackerPositionsContext(ctx context.Context, trackerID string, start, end time.Time) (*GetTrackerPositionsResponse, error) {
+	version := t.positionsAPIVersion.Load()
+	if version == 3 || t.positionsAPIPinned {
+		return t.getTrackerPositions(ctx, version, trackerID, start, end)
+	}
+	resp, err := t.getTrackerPositions(ctx, 4, trackerID, start, end)
+	if err == nil || !isEndpointUnavailable(err) {
+		if err == nil {
+			t.positionsAPIVersion.Store(4)
+		}
+		return resp, err
 	}
-	var resp GetTrackerResponse
-	if err := json.Unmarshal(body, &resp); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal json response: %w", err)
+	t.getLogger().Debugf("Positions API v4 unavailable, falling back to v3: %v", err)
+	resp, v3Err := t.getTrackerPositions(ctx, 3, trackerID, start, end)
+	if v3Err != nil {
+		// the v4 error is the one that matters if v3 doesn't work either.
+		return nil, err
 	}
-	return &resp, nil
+	t.positionsAPIVersion.Store(3)
+	return resp, nil
 }
 
-func (t *Tractive) GetTrackerPositions(trackerID string, start, end time.Time) (*GetTrackerPositionsResponse, error) {
-	u := getTractiveURL()
-	// FIXME: using API version 3 because I couldn't find the equivalent method for API v4
-	u.Path = "/3/tracker/" + trackerID + "/positions"
+// WithPositionsAPIVersion pins the API version (3 or 4) used to get the
+// position history, instead of negotiating it.
+func WithPositionsAPIVersion(version int32) Option {
+	return func(t *Tractive) {
+		t.positionsAPIVersion.Store(version)
+		t.positionsAPIPinned = version != 0
+	}
+}
+
+func (t *Tractive) getTrackerPositions(ctx context.Context, version int32, trackerID string, start, end time.Time) (*GetTrackerPositionsResponse, error) {
+	u := t.tractiveURL(fmt.Sprintf("/%d/tracker/%s/positions", version, trackerID))
 	q := u.Query()
 	q.Add("time_from", strconv.FormatInt(start.Unix(), 10))
 	q.Add("time_to", strconv.FormatInt(end.Unix(), 10))
 	q.Add("format", "json_segments")
 	u.RawQuery = q.Encode()
-	body, err := tractiveRequest("GET", u, t.Token)
-	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
+	return callAPI[GetTrackerPositionsResponse](ctx, t, http.MethodGet, u, nil)
+}
+
+// isEndpointUnavailable tells whether err means that the requested endpoint
+// doesn't exist, as opposed to a failure of the request. A 404 carrying an
+// API error body is about a missing resource, e.g. an unknown tracker, not a
+// missing endpoint.
+func isEndpointUnavailable(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
 	}
-	var resp GetTrackerPositionsResponse
-	if err := json.Unmarshal(body, &resp); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal json response: %w", err)
+	switch apiErr.StatusCode {
+	case http.StatusNotFound:
+		return apiErr.Code == 0 && apiErr.Message == ""
+	case http.StatusMethodNotAllowed, http.StatusGone, http.StatusNotImplemented:
+		return true
 	}
-	return &resp, nil
+	return false
 }
@@ -1,14 +1,18 @@
 package tractive
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
-	"net/http/httputil"
 	"net/url"
+	"path"
+	"sync"
+	"sync/atomic"
 	"time"
-
-	"github.com/sirupsen/logrus"
 )
 
 const (
@@ -17,6 +21,16 @@ const (
 	ClientID       = "6536c228870a3c8857d452e8"
 )
 
+// TokenExpiryMargin is how long before its expiration an access token is
+// renewed, so that in-flight requests don't race with the expiry.
+const TokenExpiryMargin = time.Minute
+
+// DefaultTimeout is the timeout of DefaultHTTPClient.
+const DefaultTimeout = 30 * time.Second
+
+// DefaultHTTPClient is the HTTP client used to talk to the Tractive API.
+var DefaultHTTPClient = &http.Client{Timeout: DefaultTimeout}
+
 type Tractive struct {
 	Username       string
 	Password       string
@@ -24,58 +38,209 @@ type Tractive struct {
 	TokenExpiresAt time.Time
 	UserID         string
 	ClientID       string
+
+	// RetryPolicy controls how requests failing with 429 or 5xx are
+	// retried. If nil, DefaultRetryPolicy is used.
+	RetryPolicy *RetryPolicy
+	// RateLimiter, if set, limits the rate of requests sent to the API.
+	RateLimiter *RateLimiter
+
+	// clientID is the client ID sent to the API, as opposed to ClientID,
+	// which is the one the session was issued to.
+	clientID   string
+	httpClient *http.Client
+	baseURL    *url.URL
+	channelURL *url.URL
+	userAgent  string
+	logger     Logger
+
+	redactCoordinates bool
+	tokenStore        TokenStore
+
+	// positionsAPIVersion is the API version of the positions endpoint, or
+	// 0 if not negotiated yet.
+	positionsAPIVersion atomic.Int32
+	// positionsAPIPinned disables the negotiation of positionsAPIVersion.
+	positionsAPIPinned bool
+
+	// mu serializes token renewals, so that concurrent callers share a
+	// single re-authentication.
+	mu sync.Mutex
 }
 
-func getTractiveURL() url.URL {
-	return url.URL{
+// tractiveURL returns the URL of the given API path.
+func (t *Tractive) tractiveURL(p string) url.URL {
+	u := url.URL{
 		Scheme: TractiveScheme,
 		Host:   TractiveHost,
 	}
+	if t.baseURL != nil {
+		u = *t.baseURL
+	}
+	u.Path = path.Join("/", u.Path, p)
+	return u
 }
 
-func tractiveRequest(method string, u url.URL, token string) ([]byte, error) {
-	client := &http.Client{}
-	req, err := http.NewRequest(method, u.String(), nil)
+// callAPI executes an authenticated request, sending `in` as JSON body unless
+// it is nil, and decodes the JSON response into a T. An empty response
+// decodes into the zero value of T.
+func callAPI[T any](ctx context.Context, t *Tractive, method string, u url.URL, in interface{}) (*T, error) {
+	body, err := t.request(ctx, method, u, in)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create http request: %w", err)
+		return nil, fmt.Errorf("request failed: %w", err)
 	}
-	req.Header.Set("X-Tractive-Client", ClientID)
-	req.Header.Set("Content-Type", "application/json")
-	if token != "" {
-		req.Header.Set("Authorization", "Bearer "+token)
+	var resp T
+	if len(bytes.TrimSpace(body)) > 0 {
+		if err := json.Unmarshal(body, &resp); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal json response: %w", err)
+		}
 	}
+	return &resp, nil
+}
 
-	// only if debug requested
-	if logrus.GetLevel() == logrus.DebugLevel {
-		reqDump, err := httputil.DumpRequestOut(req, true)
+// request executes an authenticated request, sending `in` as JSON body unless
+// it is nil. If the token is expired, or if the API rejects it, the client
+// re-authenticates once with the stored credentials and retries.
+func (t *Tractive) request(ctx context.Context, method string, u url.URL, in interface{}) ([]byte, error) {
+	var reqBody []byte
+	if in != nil {
+		var err error
+		reqBody, err = json.Marshal(in)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal json request: %w", err)
+		}
+	}
+	token, err := t.currentToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+	body, err := t.do(ctx, method, u, token, reqBody)
+	if errors.Is(err, ErrUnauthorized) && t.hasCredentials() {
+		token, err = t.renewToken(ctx, token)
 		if err != nil {
-			logrus.Warningf("Failed to dump HTTP request: %v", err)
-		} else {
-			logrus.Debugf("HTTP REQUEST:\n%s\n", string(reqDump))
+			return nil, err
 		}
+		body, err = t.do(ctx, method, u, token, reqBody)
+	}
+	return body, err
+}
+
+// do executes a request with the given body, which may be nil, honouring the
+// rate limiter and retrying according to the retry policy.
+func (t *Tractive) do(ctx context.Context, method string, u url.URL, token string, reqBody []byte) ([]byte, error) {
+	policy := DefaultRetryPolicy
+	if t.RetryPolicy != nil {
+		policy = *t.RetryPolicy
+	}
+	for attempt := 0; ; attempt++ {
+		if err := t.RateLimiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+		body, err := t.tractiveRequest(ctx, method, u, token, reqBody)
+		if err == nil || attempt >= policy.MaxRetries || !isRetryable(method, err) {
+			return body, err
+		}
+		var retryAfter time.Duration
+		var apiErr *APIError
+		if errors.As(err, &apiErr) {
+			retryAfter = apiErr.RetryAfter
+		}
+		wait := policy.backoff(attempt, retryAfter)
+		t.getLogger().Debugf("Request to %s failed: %v. Retrying in %s (%d/%d)", u.Path, err, wait, attempt+1, policy.MaxRetries)
+		if err := sleepContext(ctx, wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// currentToken returns the access token, renewing it first if it is about to
+// expire and credentials are available.
+func (t *Tractive) currentToken(ctx context.Context) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.TokenExpiresAt.IsZero() || time.Now().Before(t.TokenExpiresAt.Add(-TokenExpiryMargin)) || !t.hasCredentials() {
+		return t.Token, nil
+	}
+	if err := t.reauthenticate(ctx); err != nil {
+		return "", err
+	}
+	return t.Token, nil
+}
+
+// renewToken re-authenticates after `stale` was rejected by the API. If
+// another caller has already replaced it in the meantime, the new token is
+// returned without authenticating again.
+func (t *Tractive) renewToken(ctx context.Context, stale string) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.Token != stale {
+		return t.Token, nil
 	}
+	if err := t.reauthenticate(ctx); err != nil {
+		return "", err
+	}
+	return t.Token, nil
+}
 
-	resp, err := client.Do(req)
+// reauthenticate obtains a new token with the stored credentials. It must be
+// called with t.mu held. Only the token is replaced: the user and client IDs
+// are read without locking by concurrent requests, and don't change for a
+// given account.
+func (t *Tractive) reauthenticate(ctx context.Context) error {
+	t.getLogger().Debugf("Re-authenticating as %q", t.Username)
+	ar, err := t.login(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute http request: %w", err)
+		return fmt.Errorf("re-authentication failed: %w", err)
+	}
+	t.setToken(ar)
+	t.saveSession()
+	return nil
+}
+
+func (t *Tractive) hasCredentials() bool {
+	return t.Username != "" && t.Password != ""
+}
+
+func (t *Tractive) tractiveRequest(ctx context.Context, method string, u url.URL, token string, reqBody []byte) ([]byte, error) {
+	var r io.Reader
+	if reqBody != nil {
+		r = bytes.NewReader(reqBody)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create http request: %w", err)
+	}
+	req.Header.Set("X-Tractive-Client", t.getClientID())
+	req.Header.Set("Content-Type", "application/json")
+	if t.userAgent != "" {
+		req.Header.Set("User-Agent", t.userAgent)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
 	}
 
 	// only if debug requested
-	if logrus.GetLevel() == logrus.DebugLevel {
-		respDump, err := httputil.DumpResponse(resp, true)
-		if err != nil {
-			logrus.Warningf("Failed to dump HTTP request: %v", err)
-		} else {
-			fmt.Printf("HTTP RESPONSE:\n%s\n", string(respDump))
-		}
+	if t.debugEnabled() {
+		t.traceRequest(req, reqBody)
 	}
 
-	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("http status is %s, expected 200 OK", resp.Status)
+	resp, err := t.getHTTPClient().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute http request: %w", err)
 	}
+	defer resp.Body.Close()
+
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get http body: %w", err)
 	}
+
+	// only if debug requested
+	if t.debugEnabled() {
+		t.traceResponse(resp, body)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, newAPIError(resp, body)
+	}
 	return body, nil
 }